@@ -7,10 +7,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/cilium/release/pkg/github"
 	"github.com/cilium/release/pkg/types"
+	"github.com/shurcooL/githubv4"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +31,62 @@ type ChangeLogConfig struct {
 	ExcludeLabels       []string
 	ExcludePRReferences bool
 	SkipHeader          bool
+	Output              string
+	TemplateFile        string
+	TargetVer           string
+	ReleaseDate         string
+	RSTLinkStyle        string
+	// GroupByLabelPrefix groups the entries of each section by their label
+	// with this prefix, e.g. "area/". GroupTitles maps those labels to the
+	// headings of their groups.
+	GroupByLabelPrefix string
+	GroupTitles        map[string]string
+	// NewContributors adds a section with the authors whose first merged PR
+	// is part of the release.
+	NewContributors bool
+	// SecurityAdvisories adds the published security advisories fixed by
+	// TargetVer at the top of the release notes.
+	SecurityAdvisories bool
+	// GroupDependencies moves the PRs updating dependencies out of their
+	// sections into a single table. They are the PRs opened by one of
+	// DependencyAuthors, with one of DependencyLabels or whose title
+	// matches one of the DependencyTitlePatterns regular expressions.
+	GroupDependencies       bool
+	DependencyAuthors       []string
+	DependencyLabels        []string
+	DependencyTitlePatterns []string
+	// GoModules adds the Go modules added, removed and changed between Base
+	// and Head, read from the go.mod and vendor/modules.txt files of
+	// RepoDirectory.
+	GoModules bool
+	// EmbargoFile lists the PRs, in addition to the ones labeled
+	// github.EmbargoedLabel, whose release notes must not be published yet.
+	// EmbargoMode sets whether they are replaced by a placeholder or held
+	// out of the release notes, unless RevealEmbargoed is set.
+	EmbargoFile     string
+	EmbargoMode     string
+	RevealEmbargoed bool
+	// RepoDirectory is the local checkout of the repository. When set, the
+	// commits are listed with git instead of the GitHub API.
+	RepoDirectory string
+	// RESTAPI fetches the PRs one commit at a time with the REST API
+	// instead of in batches with the GraphQL API.
+	RESTAPI bool
+	// ForceStateFile uses StateFile even if it was created for a different
+	// repository or commit range.
+	ForceStateFile bool
+	// StateFileAnyHead uses StateFile if it was created for the same
+	// repository and base, whatever its head.
+	StateFileAnyHead bool
+}
+
+// GQLClient returns the client passed to GenerateReleaseNotes, which is nil
+// if the PRs must be fetched with the REST API.
+func (cfg *ChangeLogConfig) GQLClient() *githubv4.Client {
+	if cfg.RESTAPI {
+		return nil
+	}
+	return github.NewGQLClient()
 }
 
 func (cfg *ChangeLogConfig) Sanitize() error {
@@ -39,6 +100,34 @@ func (cfg *ChangeLogConfig) Sanitize() error {
 	if strings.Contains(cfg.LastStable, "v") {
 		return fmt.Errorf("--last-stable can't contain letters, should be of the format 'x.y'\n")
 	}
+	if len(cfg.ReleaseDate) != 0 {
+		if _, err := time.Parse(time.DateOnly, cfg.ReleaseDate); err != nil {
+			return fmt.Errorf("--release-date should be of the format 'YYYY-MM-DD': %w\n", err)
+		}
+	}
+	if len(cfg.Output) != 0 && !slices.Contains(OutputFormats, cfg.Output) {
+		return fmt.Errorf("--output must be one of: %s\n", strings.Join(OutputFormats, ", "))
+	}
+	if cfg.SecurityAdvisories && len(cfg.TargetVer) == 0 {
+		return fmt.Errorf("--security-advisories requires --target-version\n")
+	}
+	if cfg.GoModules && len(cfg.RepoDirectory) == 0 {
+		return fmt.Errorf("--go-modules requires --repo-dir\n")
+	}
+	if len(cfg.EmbargoMode) != 0 && !slices.Contains(EmbargoModes, cfg.EmbargoMode) {
+		return fmt.Errorf("--embargo-mode must be one of: %s\n", strings.Join(EmbargoModes, ", "))
+	}
+	if cfg.RESTAPI && cfg.NewContributors {
+		return fmt.Errorf("--new-contributors can't be used with --rest-api\n")
+	}
+	if len(cfg.RSTLinkStyle) != 0 && !slices.Contains(RSTLinkStyles, cfg.RSTLinkStyle) {
+		return fmt.Errorf("--rst-link-style must be one of: %s\n", strings.Join(RSTLinkStyles, ", "))
+	}
+	for _, pattern := range cfg.DependencyTitlePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid --dependency-title-patterns %q: %w\n", pattern, err)
+		}
+	}
 	return nil
 }
 
@@ -55,27 +144,53 @@ func Command(ctx context.Context, logger *log.Logger) *cobra.Command {
 			}
 
 			ghClient := github.NewClient()
-			cl, err := GenerateReleaseNotes(ctx, ghClient, logger, cfg)
+			cl, err := GenerateReleaseNotes(ctx, ghClient, cfg.GQLClient(), logger, cfg)
 			if err != nil {
 				return err
 			}
-			cl.PrintReleaseNotes()
-			return nil
+			return cl.PrintReleaseNotesAs(os.Stdout, cfg.Output)
 		},
 	}
 	cmd.Flags().StringVar(&cfg.Base, "base", "", "Base commit / tag used to generate release notes")
 	cmd.Flags().StringVar(&cfg.Head, "head", "", "Head commit used to generate release notes")
 	cmd.Flags().StringVar(&cfg.LastStable, "last-stable", "", "When last stable version is set, it will be used to detect if a bug was already backported or not to that particular branch (e.g.: '1.5', '1.6')")
 	cmd.Flags().StringVar(&cfg.StateFile, "state-file", "release-state.json", "When set, it will use the already fetched information from a previous run")
+	cmd.Flags().BoolVar(&cfg.ForceStateFile, "force-state-file", false, "Use --state-file even if it was created for a different repository, base or head")
 	cmd.Flags().StringVar(&cfg.RepoName, "repo", "cilium/cilium", "GitHub organization and repository names separated by a slash")
+	cmd.Flags().BoolVar(&cfg.RESTAPI, "rest-api", false, "If true, fetch the PRs one commit at a time with the REST API instead of in batches with the GraphQL API")
+	cmd.Flags().StringVar(&cfg.RepoDirectory, "repo-dir", "", "Local checkout of the repository used to list the commits between base and head. If empty or if the commits can't be listed, the GitHub API is used instead")
 	cmd.Flags().StringArrayVar(&cfg.LabelFilters, "label-filter", []string{}, "Filter pull requests by labels.")
 	cmd.Flags().StringArrayVar(&cfg.ReleaseLabels, "release-labels", []string{}, "Specify release labels to consider when generating the changelog. This also defines the order of the release notes.")
 	cmd.Flags().StringArrayVar(&cfg.ExcludeLabels, "exclude-labels", []string{}, "Exclude pull requests with the specified labels.")
 	cmd.Flags().BoolVar(&cfg.ExcludePRReferences, "exclude-pr-references", false, "If true, do not include references to the PR or PR author")
 	cmd.Flags().BoolVar(&cfg.SkipHeader, "skip-header", false, "If true, do not print 'Summary of Changes' header")
+	cmd.Flags().StringVar(&cfg.TemplateFile, "template", "", "Go text/template file used to render the Markdown release notes. Defaults to the built-in template")
+	cmd.Flags().StringVar(&cfg.TargetVer, "target-version", "", "Version being released, made available to the release notes template")
+	cmd.Flags().StringVar(&cfg.ReleaseDate, "release-date", "", "Release date in YYYY-MM-DD format made available to the release notes template. Defaults to today")
+	cmd.Flags().StringVar(&cfg.GroupByLabelPrefix, "group-by-label-prefix", "", "Group the entries of each section by their label with this prefix, e.g. 'area/'. Entries without such label are grouped under 'Other'")
+	cmd.Flags().StringToStringVar(&cfg.GroupTitles, "group-title", map[string]string{}, "Heading of the group of a label, e.g. 'area/datapath=Datapath'. Defaults to the label without --group-by-label-prefix")
+	cmd.Flags().BoolVar(&cfg.NewContributors, "new-contributors", false, "If true, add a section listing the authors whose first merged PR in the repository is part of the release")
+	cmd.Flags().BoolVar(&cfg.SecurityAdvisories, "security-advisories", false, "If true, list the published security advisories patched in --target-version at the top of the release notes")
+	cmd.Flags().BoolVar(&cfg.GoModules, "go-modules", false, "If true, list the Go modules added, removed and changed between --base and --head in the go.mod and vendor/modules.txt files of --repo-dir")
+	cmd.Flags().BoolVar(&cfg.GroupDependencies, "group-dependencies", false, "If true, list the PRs updating dependencies in a single table instead of their sections")
+	cmd.Flags().StringArrayVar(&cfg.DependencyAuthors, "dependency-authors", DefaultDependencyAuthors, "Authors, without the '[bot]' suffix, of the PRs grouped by --group-dependencies")
+	cmd.Flags().StringArrayVar(&cfg.DependencyLabels, "dependency-labels", []string{}, "Labels of the PRs grouped by --group-dependencies")
+	cmd.Flags().StringArrayVar(&cfg.DependencyTitlePatterns, "dependency-title-patterns", []string{}, "Regular expressions matching the titles of the PRs grouped by --group-dependencies")
+	cmd.Flags().StringVar(&cfg.EmbargoFile, "embargo-file", "", "YAML file listing the PRs whose release notes are embargoed, in addition to the ones labeled "+github.EmbargoedLabel)
+	cmd.Flags().StringVar(&cfg.EmbargoMode, "embargo-mode", EmbargoPlaceholder, fmt.Sprintf("How the embargoed release notes are published. Accepted values: %s", strings.Join(EmbargoModes, ", ")))
+	cmd.Flags().BoolVar(&cfg.RevealEmbargoed, "reveal-embargoed", false, "If true, publish the embargoed release notes as is, e.g. once their advisories are published")
+	cmd.Flags().StringVar(&cfg.RSTLinkStyle, "rst-link-style", RSTLinkRole, fmt.Sprintf("How PRs are referenced with --output=%s. Accepted values: %s", OutputRST, strings.Join(RSTLinkStyles, ", ")))
+	cmd.Flags().StringVar(&cfg.Output, "output", OutputMarkdown, fmt.Sprintf("Output format of the release notes. Accepted values: %s", strings.Join(OutputFormats, ", ")))
 
 	for _, flag := range []string{"base", "head", "repo"} {
 		cobra.MarkFlagRequired(cmd.Flags(), flag)
 	}
+
+	cmd.AddCommand(
+		diffCommand(ctx, logger),
+		embargoCommand(logger),
+		lintCommand(ctx, logger),
+		previewCommand(ctx, logger),
+	)
 	return cmd
 }
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultDependencyAuthors are the logins, without their "[bot]" suffix, of
+// the bots opening the PRs that update dependencies.
+var DefaultDependencyAuthors = []string{"renovate", "cilium-renovate", "dependabot"}
+
+// DependencyUpdate is a PR that updates a dependency, grouped with
+// --group-dependencies. The dependency and its versions are parsed from the
+// PR title, they are empty if the title doesn't have the format used by
+// Renovate or Dependabot.
+type DependencyUpdate struct {
+	Entry      `yaml:",inline"`
+	Dependency string `json:"dependency,omitempty" yaml:"dependency,omitempty"`
+	OldVersion string `json:"oldVersion,omitempty" yaml:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty" yaml:"newVersion,omitempty"`
+}
+
+// dependencyTitleRegex matches the titles of the PRs opened by Renovate, e.g.
+// "chore(deps): update module golang.org/x/sys to v0.24.0 (v1.16)", and by
+// Dependabot, e.g. "Bump golang.org/x/net from 0.17.0 to 0.23.0".
+var dependencyTitleRegex = regexp.MustCompile(`(?i)\b(?:bump|update)(?: module| dependency)? (\S+)(?: docker tag| docker digest| digest)?(?: from (\S+))? to (\S+)`)
+
+// parseDependencyTitle returns the dependency updated by a PR and its old
+// and new versions, the old version being empty if the title doesn't include
+// it.
+func parseDependencyTitle(title string) (dependency, oldVersion, newVersion string, ok bool) {
+	m := dependencyTitleRegex.FindStringSubmatch(title)
+	if m == nil {
+		return "", "", "", false
+	}
+	trim := func(s string) string { return strings.TrimRight(s, ".,;:") }
+	return m[1], trim(m[2]), trim(m[3]), true
+}
+
+// entryTitle returns the title of the PR of e. State files created before PR
+// titles were recorded only have the release note, which defaults to the
+// title.
+func entryTitle(e Entry) string {
+	if len(e.Title) == 0 {
+		return e.ReleaseNote
+	}
+	return e.Title
+}
+
+func newDependencyUpdate(e Entry) DependencyUpdate {
+	du := DependencyUpdate{Entry: e}
+	du.Dependency, du.OldVersion, du.NewVersion, _ = parseDependencyTitle(entryTitle(e))
+	return du
+}
+
+// Name returns the dependency, or the release note if the dependency could
+// not be parsed from the PR title.
+func (du DependencyUpdate) Name() string {
+	if len(du.Dependency) == 0 {
+		return du.ReleaseNote
+	}
+	return du.Dependency
+}
+
+// dependencyMatcher returns a function reporting whether an entry updates a
+// dependency, or nil if --group-dependencies is not set. The patterns are
+// validated by Sanitize.
+func (cl *ChangeLog) dependencyMatcher() func(e Entry) bool {
+	if !cl.GroupDependencies {
+		return nil
+	}
+	var titlePatterns []*regexp.Regexp
+	for _, pattern := range cl.DependencyTitlePatterns {
+		titlePatterns = append(titlePatterns, regexp.MustCompile(pattern))
+	}
+	return func(e Entry) bool {
+		if slices.Contains(cl.DependencyAuthors, strings.TrimSuffix(e.Author, "[bot]")) {
+			return true
+		}
+		for _, lbl := range e.Labels {
+			if slices.Contains(cl.DependencyLabels, lbl) {
+				return true
+			}
+		}
+		for _, re := range titlePatterns {
+			if re.MatchString(entryTitle(e)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sortDependencyUpdates sorts the updates by dependency, then by PR.
+func sortDependencyUpdates(updates []DependencyUpdate) {
+	sort.Slice(updates, func(i, j int) bool {
+		a, b := strings.ToLower(updates[i].Name()), strings.ToLower(updates[j].Name())
+		if a != b {
+			return a < b
+		}
+		return entryKey(updates[i].Entry) < entryKey(updates[j].Entry)
+	})
+}
+
+// markdownTableCell escapes text so that it fits in a single Markdown table
+// cell.
+func markdownTableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.Join(strings.Fields(text), " ")
+}
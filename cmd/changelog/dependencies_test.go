@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/types"
+)
+
+func Test_parseDependencyTitle(t *testing.T) {
+	tests := []struct {
+		title      string
+		dependency string
+		oldVersion string
+		newVersion string
+		ok         bool
+	}{
+		{
+			title:      "chore(deps): update module golang.org/x/sys to v0.24.0 (v1.16)",
+			dependency: "golang.org/x/sys",
+			newVersion: "v0.24.0",
+			ok:         true,
+		},
+		{
+			title:      "chore(deps): update quay.io/lvh-images/kind docker tag to bpf-next-20240805.012837 (main)",
+			dependency: "quay.io/lvh-images/kind",
+			newVersion: "bpf-next-20240805.012837",
+			ok:         true,
+		},
+		{
+			title:      "chore(deps): update dependency cilium/cilium-cli to v0.16.15",
+			dependency: "cilium/cilium-cli",
+			newVersion: "v0.16.15",
+			ok:         true,
+		},
+		{
+			title:      "build(deps): bump golang.org/x/net from 0.17.0 to 0.23.0",
+			dependency: "golang.org/x/net",
+			oldVersion: "0.17.0",
+			newVersion: "0.23.0",
+			ok:         true,
+		},
+		{
+			title: "chore(deps): update all github action dependencies (v1.16)",
+		},
+		{
+			title: "Fix a crash",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			dependency, oldVersion, newVersion, ok := parseDependencyTitle(tt.title)
+			assert.Equal(t, tt.dependency, dependency)
+			assert.Equal(t, tt.oldVersion, oldVersion)
+			assert.Equal(t, tt.newVersion, newVersion)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestChangeLog_GroupDependencies(t *testing.T) {
+	cl := testChangeLog()
+	cl.GroupDependencies = true
+	cl.DependencyAuthors = DefaultDependencyAuthors
+	cl.DependencyLabels = []string{"dependencies"}
+	cl.listOfPrs[20] = types.PullRequest{
+		Title:        "chore(deps): update module golang.org/x/sys to v0.24.0 (v1.16)",
+		ReleaseNote:  "chore(deps): update module golang.org/x/sys to v0.24.0 (v1.16)",
+		ReleaseLabel: "release-note/misc",
+		AuthorName:   "cilium-renovate[bot]",
+	}
+	cl.listOfPrs[21] = types.PullRequest{
+		Title:        "build(deps): bump golang.org/x/net from 0.17.0 to 0.23.0",
+		ReleaseNote:  "Bump x/net to fix CVE-2023-45288",
+		ReleaseLabel: "release-note/misc",
+		AuthorName:   "carol",
+		Labels:       []string{"dependencies"},
+	}
+	// The release note is used if the title can't be parsed.
+	cl.listOfPrs[22] = types.PullRequest{
+		ReleaseNote:  "chore(deps): update all github action dependencies (v1.16)",
+		ReleaseLabel: "release-note/misc",
+		AuthorName:   "renovate",
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, "Summary of Changes\n"+
+		"------------------\n"+
+		"\n"+
+		"**Minor Changes:**\n"+
+		"* Add a feature (cilium/cilium#2, @bob)\n"+
+		"\n"+
+		"**Bugfixes:**\n"+
+		"* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)\n"+
+		"\n"+
+		"**Dependency Updates:**\n"+
+		"<details>\n"+
+		"<summary>3 dependency updates</summary>\n"+
+		"\n"+
+		"| Dependency | From | To | References |\n"+
+		"|---|---|---|---|\n"+
+		"| chore(deps): update all github action dependencies (v1.16) |  |  | cilium/cilium#22, @renovate |\n"+
+		"| `golang.org/x/net` | 0.17.0 | 0.23.0 | cilium/cilium#21, @carol |\n"+
+		"| `golang.org/x/sys` |  | v0.24.0 | cilium/cilium#20, @cilium-renovate[bot] |\n"+
+		"\n"+
+		"</details>\n", buf.String())
+
+	cl.SkipHeader = true
+	cl.ExcludePRReferences = true
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, "Minor Changes\n"+
+		"-------------\n"+
+		"\n"+
+		"* Add a feature\n"+
+		"\n"+
+		"Bugfixes\n"+
+		"--------\n"+
+		"\n"+
+		"* Fix a crash\n"+
+		"\n"+
+		"Dependency Updates\n"+
+		"------------------\n"+
+		"\n"+
+		".. list-table::\n"+
+		"   :header-rows: 1\n"+
+		"\n"+
+		"   * - Dependency\n"+
+		"     - From\n"+
+		"     - To\n"+
+		"   * - chore(deps): update all github action dependencies (v1.16)\n"+
+		"     -\n"+
+		"     -\n"+
+		"   * - ``golang.org/x/net``\n"+
+		"     - 0.17.0\n"+
+		"     - 0.23.0\n"+
+		"   * - ``golang.org/x/sys``\n"+
+		"     -\n"+
+		"     - v0.24.0\n", buf.String())
+
+	// The individual PRs are kept in the JSON output.
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputJSON))
+	var m Model
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	if assert.Len(t, m.DependencyUpdates, 3) {
+		assert.Equal(t, DependencyUpdate{
+			Entry: Entry{
+				PRNumber:     21,
+				Author:       "carol",
+				Title:        "build(deps): bump golang.org/x/net from 0.17.0 to 0.23.0",
+				ReleaseLabel: "release-note/misc",
+				Labels:       []string{"dependencies"},
+				ReleaseNote:  "Bump x/net to fix CVE-2023-45288",
+			},
+			Dependency: "golang.org/x/net",
+			OldVersion: "0.17.0",
+			NewVersion: "0.23.0",
+		}, m.DependencyUpdates[1])
+	}
+}
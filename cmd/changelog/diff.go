@@ -0,0 +1,328 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/release/pkg/github"
+	"github.com/cilium/release/pkg/persistence"
+)
+
+// DiffConfig selects the two changelogs compared by the diff subcommand. Each
+// side is read from its state file which, if a range is given, is first
+// generated, or completed, by GenerateReleaseNotes.
+type DiffConfig struct {
+	ChangeLogConfig
+
+	OldStateFile string
+	OldBase      string
+	OldHead      string
+	NewStateFile string
+	NewBase      string
+	NewHead      string
+}
+
+func (cfg *DiffConfig) Sanitize() error {
+	if len(cfg.OldStateFile) == 0 || len(cfg.NewStateFile) == 0 {
+		return fmt.Errorf("--old-state-file and --new-state-file can't be empty\n")
+	}
+	if (len(cfg.OldBase) == 0) != (len(cfg.OldHead) == 0) {
+		return fmt.Errorf("--old-base and --old-head must be set together\n")
+	}
+	if (len(cfg.NewBase) == 0) != (len(cfg.NewHead) == 0) {
+		return fmt.Errorf("--new-base and --new-head must be set together\n")
+	}
+	// The state file is validated by ChangeLogConfig.Sanitize but is set for
+	// each side separately.
+	cfg.StateFile = cfg.NewStateFile
+	return cfg.ChangeLogConfig.Sanitize()
+}
+
+// side returns the configuration used to generate one side of the diff.
+func (cfg *DiffConfig) side(stateFile, base, head string) ChangeLogConfig {
+	clCfg := cfg.ChangeLogConfig
+	clCfg.StateFile = stateFile
+	clCfg.Base = base
+	clCfg.Head = head
+	return clCfg
+}
+
+// EntryChange is an entry present on both sides of a diff whose release note,
+// release label or author changed.
+type EntryChange struct {
+	Old    Entry    `json:"old" yaml:"old"`
+	New    Entry    `json:"new" yaml:"new"`
+	Fields []string `json:"fields" yaml:"fields"`
+}
+
+// SectionDiff lists the entries added, removed and changed in a section.
+// Changed entries are listed in the section of their new release label.
+type SectionDiff struct {
+	Label   string        `json:"label" yaml:"label"`
+	Title   string        `json:"title" yaml:"title"`
+	Added   []Entry       `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed []Entry       `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed []EntryChange `json:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// entryKey identifies an entry on both sides of a diff. Backports are
+// identified by their upstream PR since the backport PR may be different.
+func entryKey(e Entry) int {
+	if e.IsBackport() {
+		return e.UpstreamPRNumber
+	}
+	return e.PRNumber
+}
+
+func entryChanges(old, new Entry) []string {
+	var fields []string
+	if old.ReleaseNote != new.ReleaseNote {
+		fields = append(fields, fmt.Sprintf("release note: %q -> %q", old.ReleaseNote, new.ReleaseNote))
+	}
+	if old.ReleaseLabel != new.ReleaseLabel {
+		fields = append(fields, fmt.Sprintf("release label: %s -> %s", old.ReleaseLabel, new.ReleaseLabel))
+	}
+	if old.Author != new.Author {
+		fields = append(fields, fmt.Sprintf("author: @%s -> @%s", old.Author, new.Author))
+	}
+	return fields
+}
+
+// diffEntry is an entry of one side of a diff along with its section label.
+type diffEntry struct {
+	prNumber int
+	label    string
+	entry    Entry
+}
+
+// diffEntries returns the entries of the sections, and dependency updates, of
+// m, in order.
+func diffEntries(m *Model) []diffEntry {
+	var entries []diffEntry
+	for _, section := range m.Sections {
+		for _, e := range section.Entries {
+			entries = append(entries, diffEntry{entryKey(e), section.Label, e})
+		}
+	}
+	// Dependency updates are diffed in the section of their release label.
+	for _, du := range m.DependencyUpdates {
+		entries = append(entries, diffEntry{entryKey(du.Entry), du.ReleaseLabel, du.Entry})
+	}
+	return entries
+}
+
+// pairEntries returns the index in old of the entry of the same PR paired
+// with each entry of new. A PR whose release note lists several changes has
+// several entries, which are paired by release note first, so that editing
+// or reordering some of them doesn't change the others, then by position.
+func pairEntries(old, new []diffEntry) map[int]int {
+	pairs := map[int]int{}
+	paired := make([]bool, len(old))
+	pair := func(match func(o, n Entry) bool) {
+		for i, n := range new {
+			if _, ok := pairs[i]; ok {
+				continue
+			}
+			for j, o := range old {
+				if !paired[j] && o.prNumber == n.prNumber && match(o.entry, n.entry) {
+					pairs[i], paired[j] = j, true
+					break
+				}
+			}
+		}
+	}
+	pair(func(o, n Entry) bool { return o.ReleaseNote == n.ReleaseNote })
+	pair(func(o, n Entry) bool { return true })
+	return pairs
+}
+
+// Diff returns the entries added, removed and changed between the sections of
+// old and new, grouped by section in the order of releaseNotesOrder.
+func Diff(old, new *Model, releaseNotesOrder []string) []SectionDiff {
+	oldEntries, newEntries := diffEntries(old), diffEntries(new)
+	pairs := pairEntries(oldEntries, newEntries)
+
+	diffs := map[string]*SectionDiff{}
+	sectionDiff := func(label string) *SectionDiff {
+		if diffs[label] == nil {
+			diffs[label] = &SectionDiff{Label: label, Title: releaseNoteTitles[label]}
+		}
+		return diffs[label]
+	}
+	// Iterate over the entries in the order in which they are printed in the
+	// release notes.
+	paired := map[int]bool{}
+	for i, de := range newEntries {
+		j, ok := pairs[i]
+		if !ok {
+			sd := sectionDiff(de.label)
+			sd.Added = append(sd.Added, de.entry)
+			continue
+		}
+		paired[j] = true
+		oldEntry := oldEntries[j].entry
+		if fields := entryChanges(oldEntry, de.entry); len(fields) != 0 {
+			sd := sectionDiff(de.label)
+			sd.Changed = append(sd.Changed, EntryChange{Old: oldEntry, New: de.entry, Fields: fields})
+		}
+	}
+	for j, de := range oldEntries {
+		if !paired[j] {
+			sd := sectionDiff(de.label)
+			sd.Removed = append(sd.Removed, de.entry)
+		}
+	}
+
+	var sectionDiffs []SectionDiff
+	for _, label := range releaseNotesOrder {
+		if sd, ok := diffs[label]; ok {
+			sectionDiffs = append(sectionDiffs, *sd)
+		}
+	}
+	return sectionDiffs
+}
+
+// PrintDiff writes the differences between the release notes of old and cl
+// into w. Added entries are prefixed with '+', removed entries with '-' and
+// changed entries with '~' followed by the fields that changed.
+func (cl *ChangeLog) PrintDiff(w io.Writer, old *ChangeLog) {
+	diffs := Diff(old.Model(), cl.Model(), cl.releaseNotesOrder())
+	if len(diffs) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+
+	bullet := func(prefix string, e Entry) string {
+		return prefix + " " + strings.TrimPrefix(cl.prReleaseNote(e), "* ")
+	}
+	var added, removed, changed int
+	for i, sd := range diffs {
+		if i != 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, markdownTitle(sd.Title))
+		for _, e := range sd.Added {
+			fmt.Fprintln(w, bullet("+", e))
+		}
+		for _, e := range sd.Removed {
+			fmt.Fprintln(w, bullet("-", e))
+		}
+		for _, c := range sd.Changed {
+			fmt.Fprintln(w, bullet("~", c.New))
+			for _, field := range c.Fields {
+				fmt.Fprintf(w, "    %s\n", field)
+			}
+		}
+		added += len(sd.Added)
+		removed += len(sd.Removed)
+		changed += len(sd.Changed)
+	}
+	fmt.Fprintf(w, "\n%d added, %d removed, %d changed\n", added, removed, changed)
+}
+
+// LoadReleaseNotes returns the release notes stored in cfg.StateFile without
+// fetching any PR from GitHub. Like GenerateReleaseNotes, it fails if another
+// process holds the lock of cfg.StateFile, or if the state file was created
+// for a different repository or commit range. The range of the state file is
+// used if cfg has none.
+func LoadReleaseNotes(logger Printer, cfg ChangeLogConfig) (*ChangeLog, error) {
+	unlock, err := persistence.Lock(cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := persistence.Load(cfg.StateFile, cfg.stateMetadata())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read persistence file: %w", err)
+	}
+	if len(state.RestoredFrom) != 0 {
+		logger.Printf("WARNING: Unable to parse state file %s, using its backup %s\n", cfg.StateFile, state.RestoredFrom)
+	}
+	if len(state.SHAs) != 0 {
+		logger.Printf("WARNING: State file %s is incomplete, %d commits were not processed\n", cfg.StateFile, len(state.SHAs))
+	}
+	if len(cfg.Base) == 0 && len(cfg.Head) == 0 {
+		cfg.Base, cfg.Head = state.Metadata.Base, state.Metadata.Head
+	}
+	if err := cfg.validateState(logger, state); err != nil {
+		return nil, err
+	}
+	embargoes, err := cfg.loadEmbargoes()
+	if err != nil {
+		return nil, err
+	}
+	return &ChangeLog{
+		ChangeLogConfig: cfg,
+		Logger:          logger,
+		prsWithUpstream: state.BackportPRs,
+		listOfPrs:       state.PullRequests,
+		graphQLNodeIDs:  state.NodeIDs,
+		firstPRs:        state.FirstPRs,
+		embargoes:       embargoes,
+	}, nil
+}
+
+func diffCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	var cfg DiffConfig
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the entries added, removed and changed between two changelogs",
+		Long: `Compares the release notes stored in two state files. If a range is given
+for a side, its state file is generated, or completed, first.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			load := func(stateFile, base, head string) (*ChangeLog, error) {
+				clCfg := cfg.side(stateFile, base, head)
+				if len(base) == 0 {
+					return LoadReleaseNotes(logger, clCfg)
+				}
+				return GenerateReleaseNotes(ctx, github.NewClient(), clCfg.GQLClient(), logger, clCfg)
+			}
+			oldCL, err := load(cfg.OldStateFile, cfg.OldBase, cfg.OldHead)
+			if err != nil {
+				return err
+			}
+			newCL, err := load(cfg.NewStateFile, cfg.NewBase, cfg.NewHead)
+			if err != nil {
+				return err
+			}
+			newCL.PrintDiff(os.Stdout, oldCL)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cfg.OldStateFile, "old-state-file", "", "State file of the old changelog")
+	cmd.Flags().StringVar(&cfg.OldBase, "old-base", "", "Base commit / tag of the old changelog. If set, --old-state-file is generated for --old-base..--old-head")
+	cmd.Flags().StringVar(&cfg.OldHead, "old-head", "", "Head commit of the old changelog")
+	cmd.Flags().StringVar(&cfg.NewStateFile, "new-state-file", "", "State file of the new changelog")
+	cmd.Flags().StringVar(&cfg.NewBase, "new-base", "", "Base commit / tag of the new changelog. If set, --new-state-file is generated for --new-base..--new-head")
+	cmd.Flags().StringVar(&cfg.NewHead, "new-head", "", "Head commit of the new changelog")
+	cmd.Flags().StringVar(&cfg.LastStable, "last-stable", "", "When last stable version is set, it will be used to detect if a bug was already backported or not to that particular branch (e.g.: '1.5', '1.6')")
+	cmd.Flags().StringVar(&cfg.RepoName, "repo", "cilium/cilium", "GitHub organization and repository names separated by a slash")
+	cmd.Flags().StringVar(&cfg.RepoDirectory, "repo-dir", "", "Local checkout of the repository used to list the commits of the ranges")
+	cmd.Flags().BoolVar(&cfg.RESTAPI, "rest-api", false, "If true, fetch the PRs one commit at a time with the REST API instead of in batches with the GraphQL API")
+	cmd.Flags().BoolVar(&cfg.ForceStateFile, "force-state-file", false, "Use the state files even if they were created for a different repository, base or head")
+	cmd.Flags().StringArrayVar(&cfg.LabelFilters, "label-filter", []string{}, "Filter pull requests by labels.")
+	cmd.Flags().StringArrayVar(&cfg.ReleaseLabels, "release-labels", []string{}, "Specify release labels to consider when generating the changelog. This also defines the order of the release notes.")
+	cmd.Flags().StringArrayVar(&cfg.ExcludeLabels, "exclude-labels", []string{}, "Exclude pull requests with the specified labels.")
+	cmd.Flags().BoolVar(&cfg.ExcludePRReferences, "exclude-pr-references", false, "If true, do not include references to the PR or PR author")
+
+	for _, flag := range []string{"old-state-file", "new-state-file"} {
+		cobra.MarkFlagRequired(cmd.Flags(), flag)
+	}
+	return cmd
+}
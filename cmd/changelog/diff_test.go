@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/persistence"
+	"github.com/cilium/release/pkg/types"
+)
+
+func TestChangeLog_PrintDiff(t *testing.T) {
+	old := testChangeLog()
+	cl := testChangeLog()
+	// Upstream PR 1 is backported again with another note and label.
+	cl.prsWithUpstream = types.BackportPRs{
+		11: {
+			1: {
+				ReleaseNote:  "Fix a crash on startup",
+				ReleaseLabel: "release-note/minor",
+				AuthorName:   "alice",
+				Labels:       []string{"release-note/minor"},
+			},
+		},
+	}
+	delete(cl.listOfPrs, 2)
+	cl.listOfPrs[4] = types.PullRequest{
+		ReleaseNote:  "Add another feature",
+		ReleaseLabel: "release-note/minor",
+		AuthorName:   "dave",
+		Labels:       []string{"release-note/minor"},
+	}
+
+	var buf bytes.Buffer
+	cl.PrintDiff(&buf, old)
+	assert.Equal(t, `**Minor Changes:**
++ Add another feature (cilium/cilium#4, @dave)
+- Add a feature (cilium/cilium#2, @bob)
+~ Fix a crash on startup (Backport PR cilium/cilium#11, Upstream PR cilium/cilium#1, @alice)
+    release note: "Fix a crash" -> "Fix a crash on startup"
+    release label: release-note/bug -> release-note/minor
+
+1 added, 1 removed, 1 changed
+`, buf.String())
+
+	buf.Reset()
+	old.PrintDiff(&buf, testChangeLog())
+	assert.Equal(t, "No changes\n", buf.String())
+}
+
+func TestChangeLog_PrintDiffSplitEntries(t *testing.T) {
+	pr := func(note string) types.PullRequest {
+		return types.PullRequest{
+			ReleaseNote:  note,
+			ReleaseLabel: "release-note/minor",
+			AuthorName:   "dave",
+			Labels:       []string{"release-note/minor"},
+		}
+	}
+	old := testChangeLog()
+	old.listOfPrs[4] = pr("* Add foo\n* Add bar\n* Add baz")
+	// Editing one bullet moves it after the others, which are unchanged.
+	cl := testChangeLog()
+	cl.listOfPrs[4] = pr("* Add foo\n* Support bar in the CLI\n* Add baz")
+
+	var buf bytes.Buffer
+	cl.PrintDiff(&buf, old)
+	assert.Equal(t, `**Minor Changes:**
+~ Support bar in the CLI (cilium/cilium#4, @dave)
+    release note: "Add bar" -> "Support bar in the CLI"
+
+0 added, 0 removed, 1 changed
+`, buf.String())
+}
+
+func TestLoadReleaseNotes(t *testing.T) {
+	want := testChangeLog()
+	file := filepath.Join(t.TempDir(), "state.json")
+	err := persistence.Store(file, &persistence.State{
+		Metadata:     persistence.Metadata{Owner: "cilium", Repo: "cilium", Base: "v1.16.0", Head: "v1.16.1"},
+		BackportPRs:  want.prsWithUpstream,
+		PullRequests: want.listOfPrs,
+	})
+	assert.NoError(t, err)
+
+	cfg := want.ChangeLogConfig
+	cfg.Owner, cfg.Repo = "cilium", "cilium"
+	cfg.StateFile = file
+	cfg.Base, cfg.Head = "", ""
+	cl, err := LoadReleaseNotes(log.New(io.Discard, "", 0), cfg)
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+	assert.Equal(t, "v1.16.0", cl.Base)
+	assert.Equal(t, "v1.16.1", cl.Head)
+	assert.Equal(t, want.Model(), cl.Model())
+
+	// The state file must match the given range and repository.
+	cfg.Base, cfg.Head = "v1.15.0", "v1.16.1"
+	_, err = LoadReleaseNotes(log.New(io.Discard, "", 0), cfg)
+	assert.ErrorIs(t, err, persistence.ErrMetadataMismatch)
+	cfg.Base, cfg.Head = "", ""
+	cfg.Repo = "hubble"
+	_, err = LoadReleaseNotes(log.New(io.Discard, "", 0), cfg)
+	assert.ErrorIs(t, err, persistence.ErrMetadataMismatch)
+
+	// Unless --force-state-file is set.
+	cfg.ForceStateFile = true
+	_, err = LoadReleaseNotes(log.New(io.Discard, "", 0), cfg)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cilium/release/pkg/github"
+)
+
+const (
+	// EmbargoPlaceholder replaces the release notes of embargoed PRs.
+	EmbargoPlaceholder = "placeholder"
+	// EmbargoHold leaves embargoed PRs out of the release notes.
+	EmbargoHold = "hold"
+)
+
+var EmbargoModes = []string{EmbargoPlaceholder, EmbargoHold}
+
+// defaultEmbargoPlaceholder is the release note of embargoed PRs that don't
+// have their own placeholder in the embargo file.
+const defaultEmbargoPlaceholder = "Security fix, the details will be disclosed in an upcoming security advisory"
+
+// Embargo is a PR whose release note must not be published until its
+// security advisory is.
+type Embargo struct {
+	// PR is the number of the PR, or of the upstream PR for backports.
+	PR int `yaml:"pr"`
+	// Advisory is the ID of the security advisory, e.g. "GHSA-xxxx-xxxx-xxxx".
+	Advisory string `yaml:"advisory,omitempty"`
+	// Placeholder is published instead of the release note.
+	Placeholder string `yaml:"placeholder,omitempty"`
+}
+
+// embargoFile is the format of --embargo-file, e.g.:
+//
+//	embargoed:
+//	- pr: 12345
+//	  advisory: GHSA-xxxx-xxxx-xxxx
+//	  placeholder: Fix a crash of the agent
+type embargoFile struct {
+	Embargoed []Embargo `yaml:"embargoed"`
+}
+
+// loadEmbargoes returns the embargoes listed in --embargo-file, indexed by
+// PR number.
+func (cfg *ChangeLogConfig) loadEmbargoes() (map[int]Embargo, error) {
+	embargoes := map[int]Embargo{}
+	if len(cfg.EmbargoFile) == 0 {
+		return embargoes, nil
+	}
+	data, err := os.ReadFile(cfg.EmbargoFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read embargo file: %w", err)
+	}
+	var f embargoFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to parse embargo file %s: %w", cfg.EmbargoFile, err)
+	}
+	for _, e := range f.Embargoed {
+		embargoes[e.PR] = e
+	}
+	return embargoes, nil
+}
+
+// embargo returns the embargo of the entry, if it has the embargoed label or
+// is listed in --embargo-file.
+func (cl *ChangeLog) embargo(e Entry) (Embargo, bool) {
+	for _, prNumber := range []int{entryKey(e), e.PRNumber} {
+		if embargo, ok := cl.embargoes[prNumber]; ok {
+			return embargo, true
+		}
+	}
+	if slices.Contains(e.Labels, github.EmbargoedLabel) {
+		return Embargo{PR: entryKey(e)}, true
+	}
+	return Embargo{}, false
+}
+
+// redactEmbargoed returns the entries with the release notes of the embargoed
+// ones replaced by their placeholder, and marked as Embargoed, or without the embargoed entries with
+// --embargo-mode=hold. Entries are returned as is with --reveal-embargoed.
+func (cl *ChangeLog) redactEmbargoed(entries []Entry) []Entry {
+	if cl.RevealEmbargoed {
+		return entries
+	}
+	redacted := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		embargo, ok := cl.embargo(e)
+		switch {
+		case !ok:
+			redacted = append(redacted, e)
+		case cl.EmbargoMode != EmbargoHold:
+			placeholder := embargo.Placeholder
+			if len(placeholder) == 0 {
+				placeholder = defaultEmbargoPlaceholder
+			}
+			e.Title, e.ReleaseNote, e.UpgradeNotes = placeholder, placeholder, ""
+			e.Embargoed = true
+			redacted = append(redacted, e)
+		}
+	}
+	return redacted
+}
+
+// EmbargoedEntry is an entry of the release notes that is embargoed, along
+// with its original release note.
+type EmbargoedEntry struct {
+	Entry
+	Advisory string
+}
+
+// embargoedEntries returns the embargoed entries, with their original
+// release notes, sorted by PR number, and the PRs of --embargo-file that
+// aren't part of the release notes.
+func (cl *ChangeLog) embargoedEntries() ([]EmbargoedEntry, []int) {
+	listOfPRs, prsWithUpstream := cl.filteredPRs()
+	entries := backportEntries(prsWithUpstream)
+	for _, prID := range slices.Sorted(maps.Keys(listOfPRs)) {
+		entries = append(entries, newEntry(listOfPRs[prID], prID, 0))
+	}
+
+	var embargoed []EmbargoedEntry
+	found := map[int]bool{}
+	for _, e := range entries {
+		embargo, ok := cl.embargo(e)
+		if !ok {
+			continue
+		}
+		found[embargo.PR] = true
+		embargoed = append(embargoed, EmbargoedEntry{Entry: e, Advisory: embargo.Advisory})
+	}
+	slices.SortFunc(embargoed, func(a, b EmbargoedEntry) int {
+		return entryKey(a.Entry) - entryKey(b.Entry)
+	})
+
+	var missing []int
+	for _, prNumber := range slices.Sorted(maps.Keys(cl.embargoes)) {
+		if !found[prNumber] {
+			missing = append(missing, prNumber)
+		}
+	}
+	return embargoed, missing
+}
+
+// PrintEmbargoReport writes into w the embargoed entries, with their original
+// release notes, and how they are published.
+func (cl *ChangeLog) PrintEmbargoReport(w io.Writer) {
+	embargoed, missing := cl.embargoedEntries()
+	status := "replaced by a placeholder"
+	switch {
+	case cl.RevealEmbargoed:
+		status = "revealed by --reveal-embargoed"
+	case cl.EmbargoMode == EmbargoHold:
+		status = "held out of the release notes"
+	}
+
+	if len(embargoed) == 0 {
+		fmt.Fprintln(w, "No embargoed PRs")
+	} else {
+		fmt.Fprintf(w, "Embargoed PRs, %s:\n", status)
+	}
+	for _, e := range embargoed {
+		advisory := ""
+		if len(e.Advisory) != 0 {
+			advisory = " (" + e.Advisory + ")"
+		}
+		fmt.Fprintf(w, "%s%s\n", cl.prReleaseNote(e.Entry), advisory)
+	}
+	if len(missing) != 0 {
+		fmt.Fprintf(w, "\nPRs of %s that are not part of the release notes:\n", cl.EmbargoFile)
+		for _, prNumber := range missing {
+			fmt.Fprintf(w, "* #%d\n", prNumber)
+		}
+	}
+}
+
+// printEmbargoed logs the PRs whose release notes were redacted.
+func (cl *ChangeLog) printEmbargoed() {
+	if cl.RevealEmbargoed {
+		return
+	}
+	embargoed, _ := cl.embargoedEntries()
+	if len(embargoed) == 0 {
+		return
+	}
+	cl.Logger.Printf("\n\033[1mNOTICE\033[0m: The release notes of the following PRs are embargoed, " +
+		"use --reveal-embargoed once their advisories are published.\n")
+	for _, e := range embargoed {
+		cl.Logger.Println(cl.prReleaseNote(e.Entry))
+	}
+}
+
+// EmbargoConfig configures the embargo subcommand.
+type EmbargoConfig struct {
+	ChangeLogConfig
+}
+
+func (cfg *EmbargoConfig) Sanitize() error {
+	if len(cfg.StateFile) == 0 {
+		return fmt.Errorf("--state-file can't be empty\n")
+	}
+	if len(cfg.EmbargoMode) != 0 && !slices.Contains(EmbargoModes, cfg.EmbargoMode) {
+		return fmt.Errorf("--embargo-mode must be one of: %s\n", strings.Join(EmbargoModes, ", "))
+	}
+	return cfg.CommonConfig.Sanitize()
+}
+
+func embargoCommand(logger *log.Logger) *cobra.Command {
+	var cfg EmbargoConfig
+
+	cmd := &cobra.Command{
+		Use:   "embargo",
+		Short: "Show the PRs whose release notes are embargoed",
+		Long: `Lists the PRs of the release notes stored in a state file that have the
+` + github.EmbargoedLabel + ` label or are listed in --embargo-file, along with
+their original release notes.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			cl, err := LoadReleaseNotes(logger, cfg.ChangeLogConfig)
+			if err != nil {
+				return err
+			}
+			cl.PrintEmbargoReport(os.Stdout)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cfg.StateFile, "state-file", "release-state.json", "State file of the release notes")
+	cmd.Flags().BoolVar(&cfg.ForceStateFile, "force-state-file", false, "Use --state-file even if it was created for a different repository")
+	cmd.Flags().StringVar(&cfg.RepoName, "repo", "cilium/cilium", "GitHub organization and repository names separated by a slash")
+	cmd.Flags().StringVar(&cfg.EmbargoFile, "embargo-file", "", "YAML file listing embargoed PRs in addition to the ones labeled "+github.EmbargoedLabel)
+	cmd.Flags().StringVar(&cfg.EmbargoMode, "embargo-mode", EmbargoPlaceholder, fmt.Sprintf("How embargoed release notes are published. Accepted values: %s", strings.Join(EmbargoModes, ", ")))
+	cmd.Flags().BoolVar(&cfg.RevealEmbargoed, "reveal-embargoed", false, "If true, publish the release notes of embargoed PRs as is")
+	cmd.Flags().StringArrayVar(&cfg.LabelFilters, "label-filter", []string{}, "Filter pull requests by labels.")
+	cmd.Flags().StringArrayVar(&cfg.ExcludeLabels, "exclude-labels", []string{}, "Exclude pull requests with the specified labels.")
+	return cmd
+}
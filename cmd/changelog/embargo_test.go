@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/github"
+)
+
+func TestChangeLog_Embargo(t *testing.T) {
+	embargoFile := filepath.Join(t.TempDir(), "embargo.yaml")
+	assert.NoError(t, os.WriteFile(embargoFile, []byte(`embargoed:
+- pr: 1
+  advisory: GHSA-xxxx-xxxx-xxxx
+  placeholder: Fix a crash of the agent
+- pr: 42
+`), 0644))
+
+	cl := testChangeLog()
+	cl.SkipHeader = true
+	cl.EmbargoFile = embargoFile
+	pr := cl.listOfPrs[2]
+	pr.ReleaseNote = "Add a feature\n- with a detail"
+	pr.UpgradeNotes = "Restart the agents"
+	pr.Labels = append(pr.Labels, github.EmbargoedLabel)
+	cl.listOfPrs[2] = pr
+	upstreamPR := cl.prsWithUpstream[10][1]
+	upstreamPR.ReleaseNote = "Fix a crash in the policy engine"
+	cl.prsWithUpstream[10][1] = upstreamPR
+
+	var err error
+	cl.embargoes, err = cl.loadEmbargoes()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesForWriter(&buf))
+	// The placeholders don't reference the PRs nor their authors.
+	assert.Equal(t, `
+**Minor Changes:**
+* Security fix, the details will be disclosed in an upcoming security advisory
+
+**Bugfixes:**
+* Fix a crash of the agent
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, `Minor Changes
+-------------
+
+* Security fix, the details will be disclosed in an upcoming security advisory
+
+Bugfixes
+--------
+
+* Fix a crash of the agent
+`, buf.String())
+
+	cl.EmbargoMode = EmbargoHold
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesForWriter(&buf))
+	assert.Equal(t, "", buf.String())
+
+	buf.Reset()
+	cl.PrintEmbargoReport(&buf)
+	assert.Equal(t, `Embargoed PRs, held out of the release notes:
+* Fix a crash in the policy engine (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice) (GHSA-xxxx-xxxx-xxxx)
+* Add a feature (cilium/cilium#2, @bob)
+  - with a detail
+
+PRs of `+embargoFile+` that are not part of the release notes:
+* #42
+`, buf.String())
+
+	cl.RevealEmbargoed = true
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesForWriter(&buf))
+	assert.Equal(t, `
+**Upgrade Notes:**
+* Restart the agents (cilium/cilium#2, @bob)
+
+**Minor Changes:**
+* Add a feature (cilium/cilium#2, @bob)
+  - with a detail
+
+**Bugfixes:**
+* Fix a crash in the policy engine (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+`, buf.String())
+}
+
+func TestChangeLog_EmbargoNewContributors(t *testing.T) {
+	cl := testChangeLog()
+	var logs bytes.Buffer
+	cl.Logger = log.New(&logs, "", 0)
+	cl.SkipHeader = true
+	cl.NewContributors = true
+	cl.firstPRs = map[string]int{"alice": 1, "bob": 2}
+	pr := cl.listOfPrs[2]
+	pr.Labels = append(pr.Labels, github.EmbargoedLabel)
+	cl.listOfPrs[2] = pr
+
+	// Like its placeholder, the contributors don't reference the author of
+	// the embargoed PR.
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.NotContains(t, buf.String(), "bob")
+	assert.Contains(t, buf.String(), `**New Contributors:**
+* @alice made their first contribution in cilium/cilium#1
+`)
+	assert.Contains(t, logs.String(), "NOTICE")
+
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.NotContains(t, buf.String(), "bob")
+	assert.NotContains(t, buf.String(), ":gh-pull:`2`")
+
+	cl.EmbargoMode = EmbargoHold
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.NotContains(t, buf.String(), "@bob")
+	assert.Contains(t, buf.String(), "* @alice made their first contribution in cilium/cilium#1\n")
+
+	// The redacted PRs are logged whatever the format.
+	for _, format := range []string{OutputRST, OutputJSON, OutputYAML} {
+		logs.Reset()
+		buf.Reset()
+		assert.NoError(t, cl.PrintReleaseNotesAs(&buf, format))
+		assert.NotContains(t, buf.String(), "bob", format)
+		assert.Contains(t, logs.String(), "* Add a feature (cilium/cilium#2, @bob)", format)
+	}
+}
@@ -19,26 +19,31 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	gh "github.com/google/go-github/v62/github"
 	"github.com/schollz/progressbar/v3"
+	"github.com/shurcooL/githubv4"
 
+	"github.com/cilium/release/pkg/deps"
+	"github.com/cilium/release/pkg/git"
 	"github.com/cilium/release/pkg/github"
 	"github.com/cilium/release/pkg/persistence"
 	"github.com/cilium/release/pkg/types"
 )
 
-var releaseNotes = map[string]string{
-	"release-note/security": "**Important Security Updates:**",
-	"release-note/major":    "**Major Changes:**",
-	"release-note/minor":    "**Minor Changes:**",
-	"release-note/bug":      "**Bugfixes:**",
-	"release-note/ci":       "**CI Changes:**",
-	"release-note/misc":     "**Misc Changes:**",
-	"release-note/none":     "**Other Changes:**",
+// releaseNoteTitles maps each release-note label to the title of its section
+// in the release notes.
+var releaseNoteTitles = map[string]string{
+	"release-note/security": "Important Security Updates",
+	"release-note/major":    "Major Changes",
+	"release-note/minor":    "Minor Changes",
+	"release-note/bug":      "Bugfixes",
+	"release-note/ci":       "CI Changes",
+	"release-note/misc":     "Misc Changes",
+	"release-note/none":     "Other Changes",
 }
 
 var defaultReleaseNotesOrder = []string{
@@ -58,6 +63,14 @@ type ChangeLog struct {
 	prsWithUpstream types.BackportPRs
 	listOfPrs       types.PullRequests
 	graphQLNodeIDs  types.NodeIDs
+	// firstPRs maps each author to their first merged PR in the repository.
+	firstPRs map[string]int
+	// advisories are the security advisories fixed by TargetVer.
+	advisories []github.Advisory
+	// moduleChanges are the changes of the Go modules between Base and Head.
+	moduleChanges []deps.ModuleChange
+	// embargoes are the embargoed PRs listed in --embargo-file.
+	embargoes map[int]Embargo
 }
 
 type Printer interface {
@@ -65,63 +78,95 @@ type Printer interface {
 	Println(v ...any)
 }
 
-func GenerateReleaseNotes(globalCtx context.Context, ghClient *gh.Client, logger Printer, cfg ChangeLogConfig) (*ChangeLog, error) {
+// stateMetadata returns the metadata describing the state file generated for
+// cfg.
+func (cfg *ChangeLogConfig) stateMetadata() persistence.Metadata {
+	return persistence.Metadata{
+		Owner:        cfg.Owner,
+		Repo:         cfg.Repo,
+		Base:         cfg.Base,
+		Head:         cfg.Head,
+		LabelFilters: cfg.LabelFilters,
+	}
+}
+
+// validateState returns an error if the state was stored for a different
+// repository or commit range, unless --force-state-file is set. The head is
+// not checked with StateFileAnyHead.
+func (cfg *ChangeLogConfig) validateState(logger Printer, state *persistence.State) error {
+	if state.Migrated {
+		logger.Printf("WARNING: State file %s was written by an older version of this tool, assuming it was created for %s..%s\n", cfg.StateFile, cfg.Base, cfg.Head)
+	}
+	validate := state.Metadata.Validate
+	if cfg.StateFileAnyHead {
+		validate = state.Metadata.ValidateBase
+	}
+	err := validate(cfg.stateMetadata())
+	if err == nil {
+		return nil
+	}
+	if cfg.ForceStateFile {
+		logger.Printf("WARNING: Using state file %s anyway: %s\n", cfg.StateFile, err)
+		return nil
+	}
+	return fmt.Errorf("refusing to use state file %s created on %s: %w. Remove it or use --force-state-file to use it anyway", cfg.StateFile, state.Metadata.CreatedAt.Format(time.DateTime), err)
+}
+
+// GenerateReleaseNotes retrieves all PRs merged between cfg.Base and cfg.Head.
+// If ghGQLClient is not nil, the PRs are fetched in batches with the GraphQL
+// API, otherwise they are fetched one commit at a time with the REST API.
+// cfg.StateFile is locked while the PRs are fetched and stored, so that
+// concurrent runs fail instead of clobbering it. The lock is released once
+// the release notes are generated: callers reading the state file again,
+// e.g. in a later step of a release, take it again, see LoadReleaseNotes.
+func GenerateReleaseNotes(globalCtx context.Context, ghClient *gh.Client, ghGQLClient *githubv4.Client, logger Printer, cfg ChangeLogConfig) (*ChangeLog, error) {
 	var (
 		backportPRs = types.BackportPRs{}
 		listOfPRs   = types.PullRequests{}
 		nodeIDs     = types.NodeIDs{}
 		shas        []string
+		messages    map[string]string
+		firstPRs    = map[string]int{}
+		metadata    = cfg.stateMetadata()
 	)
 
+	unlock, err := persistence.Lock(cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	if _, err := os.Stat(cfg.StateFile); err == nil {
 		logger.Printf("Found state file, resuming from stored state\n")
 
-		var err error
-		backportPRs, listOfPRs, nodeIDs, shas, err = persistence.LoadState(cfg.StateFile)
+		state, err := persistence.Load(cfg.StateFile, cfg.stateMetadata())
 		if err != nil {
 			return nil, fmt.Errorf("Unable to read persistence file: %w", err)
 		}
+		if len(state.RestoredFrom) != 0 {
+			logger.Printf("WARNING: Unable to parse state file %s, resuming from its backup %s\n", cfg.StateFile, state.RestoredFrom)
+		}
+		if err := cfg.validateState(logger, state); err != nil {
+			return nil, err
+		}
+		metadata.CreatedAt = state.Metadata.CreatedAt
+		backportPRs, listOfPRs, nodeIDs, shas = state.BackportPRs, state.PullRequests, state.NodeIDs, state.SHAs
+		if state.FirstPRs != nil {
+			firstPRs = state.FirstPRs
+		}
 	} else {
-		cont := false
-		prevHead := ""
-
-		for {
-			logger.Printf("Comparing " + cfg.Base + "..." + cfg.Head + "\n")
-			cc, _, err := ghClient.Repositories.CompareCommits(globalCtx, cfg.Owner, cfg.Repo, cfg.Base, cfg.Head, &gh.ListOptions{})
+		var err error
+		if len(cfg.RepoDirectory) != 0 {
+			shas, messages, err = localCommits(globalCtx, logger, cfg)
 			if err != nil {
-				return nil, fmt.Errorf("Unable to compare commits %s %s: %w\n", cfg.Base, cfg.Head, err)
+				logger.Printf("Unable to list commits in %s, falling back to the GitHub API: %s\n", cfg.RepoDirectory, err)
 			}
-			if prevHead == cc.Commits[len(cc.Commits)-1].GetSHA() {
-				sha := cc.Commits[0].GetSHA()
-				if sha != "" {
-					shas = append(shas, sha)
-				}
-				break
-			}
-			start := len(cc.Commits) - 1
-			if cont {
-				// We want to ignore the last sha for if the number of commits
-				// returned by github are throttled. If they are throttled
-				// we will keep comparing commits until the last commit
-				// points to the base commit.
-				start = start - 1
-			}
-			// List of commits are ordered from base to head
-			// so we want to order them from head to base
-			// For example, assuming commit SHAs are integers:
-			// compare 1...10 will return [6,7,8,9,10]
-			// We will store [10,9,8,7,6] and ask for compare 1...6
-			// This will return [6,5,4,3,2,1] which we will ignore 6
-			// since it's already stored in the list of SHAs and continue
-			for i := start; i != 0; i-- {
-				sha := cc.Commits[i].GetSHA()
-				if sha != "" {
-					shas = append(shas, sha)
-				}
+		}
+		if len(cfg.RepoDirectory) == 0 || err != nil {
+			shas, messages, err = compareCommits(globalCtx, ghClient, logger, cfg)
+			if err != nil {
+				return nil, err
 			}
-			cfg.Head = shas[len(shas)-1]
-			cont = true
-			prevHead = cc.Commits[len(cc.Commits)-1].GetSHA()
 		}
 	}
 
@@ -130,13 +175,29 @@ func GenerateReleaseNotes(globalCtx context.Context, ghClient *gh.Client, logger
 	defer bar.Finish()
 
 	output := func(foo string) { logger.Println(foo) }
-	prsWithUpstream, listOfPrs, nodeIDs, leftShas, err :=
-		github.GeneratePatchRelease(globalCtx, ghClient, cfg.Owner, cfg.Repo, bar, output, backportPRs, listOfPRs, nodeIDs, shas)
+	generatePatchRelease := func() (types.BackportPRs, types.PullRequests, types.NodeIDs, []string, error) {
+		if ghGQLClient != nil {
+			return github.GeneratePatchReleaseGraphQL(globalCtx, ghGQLClient, cfg.Owner, cfg.Repo, bar, output, backportPRs, listOfPRs, nodeIDs, shas)
+		}
+		return github.GeneratePatchRelease(globalCtx, ghClient, cfg.Owner, cfg.Repo, bar, output, backportPRs, listOfPRs, nodeIDs, shas, messages)
+	}
+	prsWithUpstream, listOfPrs, nodeIDs, leftShas, err := generatePatchRelease()
 	logger.Println()
-	if err != nil {
+	var errContributors error
+	if err == nil && cfg.NewContributors {
+		errContributors = lookupFirstPRs(globalCtx, ghGQLClient, logger, cfg, prsWithUpstream, listOfPrs, firstPRs)
+	}
+	if err != nil || errContributors != nil {
 		logger.Printf("Storing state in %s before exiting due to error...\n", cfg.StateFile)
 	}
-	err2 := persistence.StoreState(cfg.StateFile, prsWithUpstream, listOfPrs, nodeIDs, leftShas)
+	err2 := persistence.Store(cfg.StateFile, &persistence.State{
+		Metadata:     metadata,
+		BackportPRs:  prsWithUpstream,
+		PullRequests: listOfPrs,
+		NodeIDs:      nodeIDs,
+		SHAs:         leftShas,
+		FirstPRs:     firstPRs,
+	})
 	if err2 == nil {
 		logger.Printf("State stored successful in %s, please use --state-file=%s in the next run to continue\n", cfg.StateFile, cfg.StateFile)
 	} else {
@@ -145,153 +206,241 @@ func GenerateReleaseNotes(globalCtx context.Context, ghClient *gh.Client, logger
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve PRs for commits: %w\n", err)
 	}
+	if errContributors != nil {
+		return nil, fmt.Errorf("unable to look up new contributors: %w\n", errContributors)
+	}
 
 	logger.Printf("\n")
 	logger.Printf("Found %d PRs and %d backport PRs!\n\n", len(listOfPrs), len(prsWithUpstream))
 
+	var advisories []github.Advisory
+	if cfg.SecurityAdvisories {
+		advisories, err = github.SecurityAdvisories(globalCtx, ghClient, cfg.Owner, cfg.Repo, cfg.TargetVer)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("Found %d security advisories patched in %s\n\n", len(advisories), cfg.TargetVer)
+	}
+
+	var moduleChanges []deps.ModuleChange
+	if cfg.GoModules {
+		moduleChanges, err = deps.DiffRefs(globalCtx, cfg.RepoDirectory, cfg.Base, cfg.Head)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("Found %d Go module changes\n\n", len(moduleChanges))
+	}
+
+	embargoes, err := cfg.loadEmbargoes()
+	if err != nil {
+		return nil, err
+	}
+
 	return &ChangeLog{
 		ChangeLogConfig: cfg,
 		Logger:          logger,
 		prsWithUpstream: prsWithUpstream,
 		listOfPrs:       listOfPrs,
 		graphQLNodeIDs:  nodeIDs,
+		firstPRs:        firstPRs,
+		advisories:      advisories,
+		moduleChanges:   moduleChanges,
+		embargoes:       embargoes,
 	}, nil
 }
 
-func (cl *ChangeLog) PrintReleaseNotesForWriter(w io.Writer) {
-	var (
-		listOfPRs       = make(types.PullRequests)
-		prsWithUpstream = make(types.BackportPRs)
-	)
-
-	// Filter the PRs by --label-filter and --exclude-labels
-	for id, pr := range cl.listOfPrs.DeepCopy() {
-		if !filterByLabels(pr.Labels, cl.LabelFilters) {
-			continue
+// lookupFirstPRs adds the first merged PR of all authors of the given PRs
+// into firstPRs, skipping the authors already looked up by a previous run.
+func lookupFirstPRs(ctx context.Context, ghGQLClient *githubv4.Client, logger Printer, cfg ChangeLogConfig, prsWithUpstream types.BackportPRs, listOfPRs types.PullRequests, firstPRs map[string]int) error {
+	if ghGQLClient == nil {
+		return fmt.Errorf("--new-contributors requires the GraphQL API")
+	}
+	seen := map[string]struct{}{}
+	var authors []string
+	addAuthor := func(pr types.PullRequest) {
+		if _, ok := firstPRs[pr.AuthorName]; ok || len(pr.AuthorName) == 0 {
+			return
 		}
-		if len(cl.ExcludeLabels) > 0 && filterByLabels(pr.Labels, cl.ExcludeLabels) {
-			continue
+		if _, ok := seen[pr.AuthorName]; ok {
+			return
 		}
-		listOfPRs[id] = pr
+		seen[pr.AuthorName] = struct{}{}
+		authors = append(authors, pr.AuthorName)
 	}
-
-	// Filter the Backport PRs by --label-filter and --exclude-labels
-	for prNumber, upstreamedPRs := range cl.prsWithUpstream.DeepCopy() {
-		for upstreamPRNumber, upstreamPR := range upstreamedPRs {
-			if !filterByLabels(upstreamPR.Labels, cl.LabelFilters) {
-				continue
-			}
-			if len(cl.ExcludeLabels) > 0 && filterByLabels(upstreamPR.Labels, cl.ExcludeLabels) {
-				continue
-			}
-			if prsWithUpstream[prNumber] == nil {
-				prsWithUpstream[prNumber] = make(types.PullRequests)
-			}
-			prsWithUpstream[prNumber][upstreamPRNumber] = upstreamPR
+	for _, pr := range listOfPRs {
+		addAuthor(pr)
+	}
+	for _, upstreamPRs := range prsWithUpstream {
+		for _, pr := range upstreamPRs {
+			addAuthor(pr)
 		}
 	}
+	if len(authors) == 0 {
+		return nil
+	}
+	sort.Strings(authors)
 
-	cl.Logger.Printf("Found %d PRs and %d backport PRs in %s\n\n", len(listOfPRs), len(prsWithUpstream), cl.StateFile)
+	logger.Printf("Looking up the first PR of %d authors\n", len(authors))
+	found, err := github.FirstMergedPRs(ctx, ghGQLClient, cfg.Owner, cfg.Repo, authors)
+	if err != nil {
+		return err
+	}
+	for author, prNumber := range found {
+		firstPRs[author] = prNumber
+	}
+	return nil
+}
 
-	if !cl.SkipHeader {
-		fmt.Fprintln(w, "Summary of Changes")
-		fmt.Fprintln(w, "------------------")
+// localCommits returns the commits between cfg.Base and cfg.Head, ordered
+// from head to base, and their messages from the git repository in
+// cfg.RepoDirectory.
+func localCommits(ctx context.Context, logger Printer, cfg ChangeLogConfig) ([]string, map[string]string, error) {
+	base, err := git.RevParse(ctx, cfg.RepoDirectory, cfg.Base)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := git.RevParse(ctx, cfg.RepoDirectory, cfg.Head)
+	if err != nil {
+		return nil, nil, err
+	}
+	mergeBase, err := git.MergeBase(ctx, cfg.RepoDirectory, base, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mergeBase != base {
+		logger.Printf("%s is not an ancestor of %s, using their merge base %s\n", cfg.Base, cfg.Head, mergeBase)
 	}
 
-	var releaseNotesOrder []string
-	if len(cl.ReleaseLabels) != 0 {
-		// Only add release notes for release labels specified by --release-labels
-		for _, label := range defaultReleaseNotesOrder {
-			if !slices.Contains(cl.ReleaseLabels, label) {
-				continue
-			}
-			releaseNotesOrder = append(releaseNotesOrder, label)
+	logger.Printf("Listing commits %s..%s in %s\n", cfg.Base, cfg.Head, cfg.RepoDirectory)
+	shas, err := git.Commits(ctx, cfg.RepoDirectory, mergeBase, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages, err := git.CommitMessages(ctx, cfg.RepoDirectory, mergeBase, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shas, messages, nil
+}
+
+// compareCommits returns the commits between cfg.Base and cfg.Head, ordered
+// from head to base, and their messages with the GitHub API.
+func compareCommits(ctx context.Context, ghClient *gh.Client, logger Printer, cfg ChangeLogConfig) ([]string, map[string]string, error) {
+	var shas []string
+	messages := map[string]string{}
+	cont := false
+	prevHead := ""
+
+	for {
+		logger.Printf("Comparing " + cfg.Base + "..." + cfg.Head + "\n")
+		cc, _, err := ghClient.Repositories.CompareCommits(ctx, cfg.Owner, cfg.Repo, cfg.Base, cfg.Head, &gh.ListOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to compare commits %s %s: %w\n", cfg.Base, cfg.Head, err)
 		}
-	} else {
-		releaseNotesOrder = defaultReleaseNotesOrder
-	}
-
-	for _, releaseLabel := range releaseNotesOrder {
-		var changelogItems []string
-		printedReleaseNoteHeader := false
-		for backportPR, listOfPRsUpstream := range prsWithUpstream {
-			for prID, pr := range listOfPRsUpstream {
-				if pr.ReleaseLabel != releaseLabel {
-					continue
-				}
-				if !printedReleaseNoteHeader {
-					fmt.Fprintln(w)
-					fmt.Fprintln(w, releaseNotes[releaseLabel])
-					printedReleaseNoteHeader = true
-				}
-
-				changelogItems = append(changelogItems, cl.prReleaseNote(pr, backportPR, &prID))
-				delete(listOfPRsUpstream, prID)
-			}
+		for _, commit := range cc.Commits {
+			messages[commit.GetSHA()] = commit.GetCommit().GetMessage()
 		}
-		for prID, pr := range listOfPRs {
-			if pr.ReleaseLabel != releaseLabel {
-				continue
-			}
-			if len(cl.LastStable) != 0 {
-				var backported bool
-				for _, bb := range pr.BackportBranches {
-					if strings.Contains(bb, cl.LastStable) {
-						backported = true
-					}
-				}
-				if backported {
-					continue
-				}
-			}
-			if !printedReleaseNoteHeader {
-				fmt.Fprintln(w)
-				fmt.Fprintln(w, releaseNotes[releaseLabel])
-				printedReleaseNoteHeader = true
+		if prevHead == cc.Commits[len(cc.Commits)-1].GetSHA() {
+			sha := cc.Commits[0].GetSHA()
+			if sha != "" {
+				shas = append(shas, sha)
 			}
-
-			changelogItems = append(changelogItems, cl.prReleaseNote(pr, prID, nil))
-			delete(listOfPRs, prID)
+			break
+		}
+		start := len(cc.Commits) - 1
+		if cont {
+			// We want to ignore the last sha for if the number of commits
+			// returned by github are throttled. If they are throttled
+			// we will keep comparing commits until the last commit
+			// points to the base commit.
+			start = start - 1
 		}
-		sort.Slice(changelogItems, func(i, j int) bool {
-			return strings.ToLower(changelogItems[i]) < strings.ToLower(changelogItems[j])
-		})
-		for _, changeLogItem := range changelogItems {
-			fmt.Fprintln(w, changeLogItem)
+		// List of commits are ordered from base to head
+		// so we want to order them from head to base
+		// For example, assuming commit SHAs are integers:
+		// compare 1...10 will return [6,7,8,9,10]
+		// We will store [10,9,8,7,6] and ask for compare 1...6
+		// This will return [6,5,4,3,2,1] which we will ignore 6
+		// since it's already stored in the list of SHAs and continue
+		for i := start; i != 0; i-- {
+			sha := cc.Commits[i].GetSHA()
+			if sha != "" {
+				shas = append(shas, sha)
+			}
 		}
+		cfg.Head = shas[len(shas)-1]
+		cont = true
+		prevHead = cc.Commits[len(cc.Commits)-1].GetSHA()
 	}
+	return shas, messages, nil
+}
+
+// PrintReleaseNotesForWriter renders the release notes into w with the
+// template set by --template, or the default Markdown template.
+func (cl *ChangeLog) PrintReleaseNotesForWriter(w io.Writer) error {
+	m := cl.Model()
+
+	if err := cl.executeTemplate(w, m); err != nil {
+		return err
+	}
+
+	cl.printAlreadyReleased(m)
+	cl.printReverted(m)
+	cl.printUnresolvedReverts(m)
+	return nil
+}
 
-	if len(listOfPRs) == 0 {
+// printAlreadyReleased logs the PRs that were left out of the release notes
+// because they were already backported to --last-stable.
+func (cl *ChangeLog) printAlreadyReleased(m *Model) {
+	if len(m.AlreadyReleased) == 0 {
 		return
 	}
 	cl.Logger.Printf("\n\033[1mNOTICE\033[0m: The following PRs were not included in the "+
 		"changelog as they were backported to branch %s and assumed to be already released.\n", cl.LastStable)
 
-	for _, releaseLabel := range releaseNotesOrder {
-		var changelogItems []string
-		printedReleaseNoteHeader := false
-		for prID, pr := range listOfPRs {
-			if pr.ReleaseLabel != releaseLabel {
-				continue
-			}
-			if !printedReleaseNoteHeader {
-				cl.Logger.Printf(releaseNotes[releaseLabel])
-				printedReleaseNoteHeader = true
-			}
-			changelogItems = append(changelogItems, cl.prReleaseNote(pr, prID, nil))
-			delete(listOfPRs, prID)
-		}
-		sort.Slice(changelogItems, func(i, j int) bool {
-			return strings.ToLower(changelogItems[i]) < strings.ToLower(changelogItems[j])
-		})
-		for _, changeLogItem := range changelogItems {
-			cl.Logger.Println(changeLogItem)
+	for _, section := range m.AlreadyReleased {
+		cl.Logger.Printf(markdownTitle(section.Title))
+		for _, entry := range section.Entries {
+			cl.Logger.Println(cl.prReleaseNote(entry))
 		}
 	}
 }
 
-func (cl *ChangeLog) PrintReleaseNotes() {
-	cl.PrintReleaseNotesForWriter(os.Stdout)
+// printReverted logs the PRs that were left out of the release notes because
+// they were reverted by another PR of the release, along with their revert.
+func (cl *ChangeLog) printReverted(m *Model) {
+	if len(m.Reverted) == 0 {
+		return
+	}
+	cl.Logger.Printf("\n\033[1mNOTICE\033[0m: The following PRs were not included in the " +
+		"changelog as they were reverted by another PR of the release.\n")
+
+	cl.Logger.Printf(markdownTitle("Reverted"))
+	for _, revert := range m.Reverted {
+		cl.Logger.Println(cl.prReleaseNote(revert.Entry))
+		cl.Logger.Println("  " + strings.Replace(cl.prReleaseNote(revert.RevertedBy), "*", "reverted by", 1))
+	}
+}
+
+// printUnresolvedReverts logs the reverts of backported commits for which no
+// PR was left out of the release notes, since they could not be matched to a
+// single entry.
+func (cl *ChangeLog) printUnresolvedReverts(m *Model) {
+	if len(m.UnresolvedReverts) == 0 {
+		return
+	}
+	cl.Logger.Printf("\n\033[1mNOTICE\033[0m: The following reverts could not be matched to a single " +
+		"entry of the backport PR they revert, check whether an entry must be removed by hand.\n")
+
+	cl.Logger.Printf(markdownTitle("Unresolved reverts"))
+	for _, e := range m.UnresolvedReverts {
+		cl.Logger.Println(cl.prReleaseNote(e))
+	}
+}
+
+func (cl *ChangeLog) PrintReleaseNotes() error {
+	return cl.PrintReleaseNotesAs(os.Stdout, OutputMarkdown)
 }
 
 // AllPRs returns all PRs that are part the changelog.
@@ -311,15 +460,50 @@ func (cl *ChangeLog) AllPRs() (map[int]struct{}, types.NodeIDs) {
 	return setOfPRs, cl.graphQLNodeIDs
 }
 
-// prReleaseNote returns the release note for a given pull request.
-func (cl *ChangeLog) prReleaseNote(pr types.PullRequest, prNumber int, upstreamPRNumber *int) string {
-	text := fmt.Sprintf("* %s", pr.ReleaseNote)
-	if !cl.ExcludePRReferences {
-		if upstreamPRNumber != nil {
-			text += fmt.Sprintf(" (Backport PR %s#%d, Upstream PR %s#%d, @%s)", cl.RepoName, prNumber, cl.RepoName, *upstreamPRNumber, pr.AuthorName)
-		} else {
-			text += fmt.Sprintf(" (%s#%d, @%s)", cl.RepoName, prNumber, pr.AuthorName)
+// backportPRReferences returns the references, formatted with link, to the
+// backport PRs of a backport entry.
+func backportPRReferences(e Entry, link func(prNumber int) string) string {
+	if len(e.BackportPRNumbers) == 0 {
+		return "Backport PR " + link(e.PRNumber)
+	}
+	refs := make([]string, 0, len(e.BackportPRNumbers))
+	for _, prNumber := range e.BackportPRNumbers {
+		refs = append(refs, link(prNumber))
+	}
+	return "Backport PRs " + strings.Join(refs, ", ")
+}
+
+func markdownTitle(title string) string {
+	return fmt.Sprintf("**%s:**", title)
+}
+
+// prReleaseNote returns the release note for a given changelog entry. The
+// lines of multi-line release notes after the first one are nested in the
+// bullet.
+func (cl *ChangeLog) prReleaseNote(e Entry) string {
+	first, rest, _ := strings.Cut(e.ReleaseNote, "\n")
+	text := fmt.Sprintf("* %s", first)
+	if !cl.ExcludePRReferences && !e.Embargoed {
+		text += fmt.Sprintf(" (%s)", markdownReferences(cl.RepoName, e))
+	}
+	if len(rest) != 0 {
+		for _, line := range strings.Split(rest, "\n") {
+			text += "\n"
+			if len(line) != 0 {
+				text += "  " + line
+			}
 		}
 	}
 	return text
 }
+
+// markdownReferences returns the references to the PRs and author of a
+// changelog entry.
+func markdownReferences(repoName string, e Entry) string {
+	if e.IsBackport() {
+		return fmt.Sprintf("%s, Upstream PR %s#%d, @%s", backportPRReferences(e, func(prNumber int) string {
+			return fmt.Sprintf("%s#%d", repoName, prNumber)
+		}), repoName, e.UpstreamPRNumber, e.Author)
+	}
+	return fmt.Sprintf("%s#%d, @%s", repoName, e.PRNumber, e.Author)
+}
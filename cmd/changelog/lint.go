@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/release/pkg/github"
+)
+
+// lintOutputText is the default, human readable, output format of the lint
+// report.
+const lintOutputText = "text"
+
+// LintConfig configures the lint subcommand.
+type LintConfig struct {
+	ChangeLogConfig
+
+	MaxReleaseNoteLength int
+	// Strict makes warnings fail the lint as well as errors.
+	Strict bool
+}
+
+func (cfg *LintConfig) Sanitize() error {
+	if (len(cfg.Base) == 0) != (len(cfg.Head) == 0) {
+		return fmt.Errorf("--base and --head must be set together\n")
+	}
+	if len(cfg.Output) != 0 && cfg.Output != lintOutputText && cfg.Output != OutputJSON {
+		return fmt.Errorf("--output must be one of: %s, %s\n", lintOutputText, OutputJSON)
+	}
+	// --output is not a release notes format here.
+	clCfg := cfg.ChangeLogConfig
+	clCfg.Output = ""
+	if err := clCfg.Sanitize(); err != nil {
+		return err
+	}
+	clCfg.Output = cfg.Output
+	cfg.ChangeLogConfig = clCfg
+	return nil
+}
+
+// lintTargets returns the PRs whose release notes end up in the changelog,
+// which for backports are the upstream PRs, and the backport PRs.
+func (cl *ChangeLog) lintTargets() ([]int, []int) {
+	listOfPRs, prsWithUpstream := cl.filteredPRs()
+
+	var prNumbers, backportPRNumbers []int
+	for prNumber := range listOfPRs {
+		prNumbers = append(prNumbers, prNumber)
+	}
+	for backportPRNumber, upstreamPRs := range prsWithUpstream {
+		backportPRNumbers = append(backportPRNumbers, backportPRNumber)
+		for upstreamPRNumber := range upstreamPRs {
+			prNumbers = append(prNumbers, upstreamPRNumber)
+		}
+	}
+	// Upstream PRs that are part of several backport PRs are linted once.
+	sort.Ints(prNumbers)
+	sort.Ints(backportPRNumbers)
+	return slices.Compact(prNumbers), backportPRNumbers
+}
+
+// addDuplicateUpstreamIssues adds a warning to the lint results of each
+// backport PR that shares an upstream PR with other backport PRs, keeping
+// results sorted by PR number.
+func (cl *ChangeLog) addDuplicateUpstreamIssues(results []github.LintResult) []github.LintResult {
+	_, prsWithUpstream := cl.filteredPRs()
+	duplicates := duplicateUpstreamPRs(prsWithUpstream)
+	for _, upstreamPRNumber := range slices.Sorted(maps.Keys(duplicates)) {
+		backportPRNumbers := duplicates[upstreamPRNumber]
+		for _, backportPRNumber := range backportPRNumbers {
+			var others []string
+			for _, other := range backportPRNumbers {
+				if other != backportPRNumber {
+					others = append(others, fmt.Sprintf("#%d", other))
+				}
+			}
+			issue := github.LintIssue{
+				Severity: github.LintWarning,
+				Rule:     "duplicate-upstream-pr",
+				Message:  fmt.Sprintf("upstream PR #%d is also part of backport PR %s, it is listed once in the release notes", upstreamPRNumber, strings.Join(others, ", ")),
+			}
+			idx := slices.IndexFunc(results, func(r github.LintResult) bool {
+				return r.PRNumber == backportPRNumber
+			})
+			if idx == -1 {
+				results = append(results, github.LintResult{PRNumber: backportPRNumber})
+				idx = len(results) - 1
+			}
+			results[idx].Issues = append(results[idx].Issues, issue)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].PRNumber < results[j].PRNumber
+	})
+	return results
+}
+
+// printLintResults writes a report of results into w and returns the number
+// of errors and warnings found.
+func (cl *ChangeLog) printLintResults(w io.Writer, results []github.LintResult) (int, int) {
+	var errors, warnings int
+	for _, result := range results {
+		fmt.Fprintf(w, "%s#%d", cl.RepoName, result.PRNumber)
+		if len(result.Author) != 0 {
+			fmt.Fprintf(w, " by @%s", result.Author)
+		}
+		if len(result.Title) != 0 {
+			fmt.Fprintf(w, ": %s", result.Title)
+		}
+		fmt.Fprintf(w, "\n  https://github.com/%s/pull/%d\n", cl.RepoName, result.PRNumber)
+		for _, issue := range result.Issues {
+			fmt.Fprintf(w, "  %s: %s: %s\n", issue.Severity, issue.Rule, issue.Message)
+			if issue.Severity == github.LintError {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+	}
+	fmt.Fprintf(w, "\nFound %d errors and %d warnings in %d PRs\n", errors, warnings, len(results))
+	return errors, warnings
+}
+
+func lintCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	var cfg LintConfig
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Report PRs with missing or invalid release note metadata",
+		Long: `Checks the release notes and release-note/* labels of all PRs that are part
+of a changelog, as well as the upstream-prs block of backport PRs and upstream
+PRs that are part of more than one backport PR. The PRs are
+taken from --base..--head or, if not set, from an existing --state-file.
+
+Exits with a non-zero code if any error is found, or any warning with --strict.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			ghGQLClient := github.NewGQLClient()
+			var (
+				cl  *ChangeLog
+				err error
+			)
+			if len(cfg.Base) != 0 {
+				cl, err = GenerateReleaseNotes(ctx, github.NewClient(), ghGQLClient, logger, cfg.ChangeLogConfig)
+			} else {
+				cl, err = LoadReleaseNotes(logger, cfg.ChangeLogConfig)
+			}
+			if err != nil {
+				return err
+			}
+
+			prNumbers, backportPRNumbers := cl.lintTargets()
+			logger.Printf("Linting %d PRs and %d backport PRs\n", len(prNumbers), len(backportPRNumbers))
+			results, err := github.LintPullRequests(ctx, ghGQLClient, cfg.Owner, cfg.Repo, prNumbers, backportPRNumbers, github.LintOptions{
+				MaxReleaseNoteLength: cfg.MaxReleaseNoteLength,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to lint PRs: %w", err)
+			}
+			results = cl.addDuplicateUpstreamIssues(results)
+
+			var errors, warnings int
+			if cfg.Output == OutputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+				errors, warnings = cl.printLintResults(io.Discard, results)
+			} else {
+				errors, warnings = cl.printLintResults(os.Stdout, results)
+			}
+			if errors != 0 || (cfg.Strict && warnings != 0) {
+				return fmt.Errorf("found %d errors and %d warnings", errors, warnings)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Base, "base", "", "Base commit / tag of the PRs to lint. If empty, the PRs are read from --state-file")
+	cmd.Flags().StringVar(&cfg.Head, "head", "", "Head commit of the PRs to lint")
+	cmd.Flags().StringVar(&cfg.StateFile, "state-file", "release-state.json", "When set, it will use the already fetched information from a previous run")
+	cmd.Flags().BoolVar(&cfg.ForceStateFile, "force-state-file", false, "Use --state-file even if it was created for a different repository, base or head")
+	cmd.Flags().StringVar(&cfg.RepoName, "repo", "cilium/cilium", "GitHub organization and repository names separated by a slash")
+	cmd.Flags().StringVar(&cfg.RepoDirectory, "repo-dir", "", "Local checkout of the repository used to list the commits between base and head. If empty or if the commits can't be listed, the GitHub API is used instead")
+	cmd.Flags().StringArrayVar(&cfg.LabelFilters, "label-filter", []string{}, "Filter pull requests by labels.")
+	cmd.Flags().StringArrayVar(&cfg.ExcludeLabels, "exclude-labels", []string{}, "Exclude pull requests with the specified labels.")
+	cmd.Flags().IntVar(&cfg.MaxReleaseNoteLength, "max-length", github.DefaultMaxReleaseNoteLength, "Release notes longer than this number of characters are reported. 0 disables the check")
+	cmd.Flags().BoolVar(&cfg.Strict, "strict", false, "If true, exit with a non-zero code on warnings too")
+	cmd.Flags().StringVar(&cfg.Output, "output", lintOutputText, fmt.Sprintf("Output format of the report. Accepted values: %s, %s", lintOutputText, OutputJSON))
+	return cmd
+}
@@ -0,0 +1,534 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cilium/release/pkg/deps"
+	"github.com/cilium/release/pkg/github"
+	"github.com/cilium/release/pkg/types"
+)
+
+// Model is the structured representation of the release notes. It is built
+// from the same filtered PRs used to print the Markdown release notes, so
+// every output format contains exactly the same entries.
+type Model struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Base       string `json:"base" yaml:"base"`
+	Head       string `json:"head" yaml:"head"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+	Date       string `json:"date" yaml:"date"`
+	// UpgradeNotes contains the entries, also listed in Sections, that have
+	// upgrade notes, sorted by PR number.
+	UpgradeNotes []Entry `json:"upgradeNotes,omitempty" yaml:"upgradeNotes,omitempty"`
+	// SecurityAdvisories is only set with --security-advisories.
+	SecurityAdvisories []github.Advisory `json:"securityAdvisories,omitempty" yaml:"securityAdvisories,omitempty"`
+	Sections           []Section         `json:"sections" yaml:"sections"`
+	// AlreadyReleased contains the PRs that were excluded from the release
+	// notes because they were backported to LastStable and are assumed to be
+	// released already.
+	AlreadyReleased []Section `json:"alreadyReleased,omitempty" yaml:"alreadyReleased,omitempty"`
+	// DependencyUpdates is only set with --group-dependencies. It contains
+	// the PRs updating dependencies, which are left out of Sections, sorted
+	// by dependency.
+	DependencyUpdates []DependencyUpdate `json:"dependencyUpdates,omitempty" yaml:"dependencyUpdates,omitempty"`
+	// Reverted contains the PRs that were excluded from the release notes
+	// because they were reverted by another PR of the release.
+	Reverted []Revert `json:"reverted,omitempty" yaml:"reverted,omitempty"`
+	// UnresolvedReverts contains the reverts of backported commits that
+	// could not be matched to a single entry of their backport PR, e.g.
+	// because it has several upstream PRs. Nothing was excluded for them.
+	UnresolvedReverts []Entry `json:"unresolvedReverts,omitempty" yaml:"unresolvedReverts,omitempty"`
+	// GoModules is only set with --go-modules.
+	GoModules []deps.ModuleChange `json:"goModules,omitempty" yaml:"goModules,omitempty"`
+	// NewContributors is only set with --new-contributors.
+	NewContributors []Contributor `json:"newContributors,omitempty" yaml:"newContributors,omitempty"`
+}
+
+// Revert is a PR, and the PR that reverted it, both part of the release.
+type Revert struct {
+	Entry      Entry `json:"entry" yaml:"entry"`
+	RevertedBy Entry `json:"revertedBy" yaml:"revertedBy"`
+}
+
+// Contributor is an author whose first merged PR is part of the release.
+type Contributor struct {
+	Author   string `json:"author" yaml:"author"`
+	PRNumber int    `json:"prNumber" yaml:"prNumber"`
+}
+
+// Section groups all entries that share the same release-note label.
+type Section struct {
+	Label   string  `json:"label" yaml:"label"`
+	Title   string  `json:"title" yaml:"title"`
+	Entries []Entry `json:"entries" yaml:"entries"`
+	// Groups splits Entries by the labels matching --group-by-label-prefix.
+	// It is empty if the entries are not grouped.
+	Groups []Group `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// Group contains the entries of a section that share a label, such as
+// area/datapath. The group of the entries without such a label has no label
+// and is always the last one of a section.
+type Group struct {
+	Label   string  `json:"label,omitempty" yaml:"label,omitempty"`
+	Title   string  `json:"title" yaml:"title"`
+	Entries []Entry `json:"entries" yaml:"entries"`
+}
+
+// otherGroupTitle is the title of the group of entries without any label
+// matching --group-by-label-prefix.
+const otherGroupTitle = "Other"
+
+// EntryGroups returns the groups of the section, or a single group without
+// title containing all entries if they are not grouped.
+func (s Section) EntryGroups() []Group {
+	if len(s.Groups) == 0 {
+		return []Group{{Entries: s.Entries}}
+	}
+	return s.Groups
+}
+
+// Entry is a single release note line.
+type Entry struct {
+	// PRNumber is the number of the PR that was merged into the range. For
+	// backports this is the backport PR.
+	PRNumber int `json:"prNumber" yaml:"prNumber"`
+	// UpstreamPRNumber is the number of the upstream PR for backports, or 0.
+	UpstreamPRNumber int `json:"upstreamPRNumber,omitempty" yaml:"upstreamPRNumber,omitempty"`
+	// BackportPRNumbers lists, in ascending order, all backport PRs of the
+	// upstream PR if it is part of more than one. PRNumber is then the
+	// first of them.
+	BackportPRNumbers []int    `json:"backportPRNumbers,omitempty" yaml:"backportPRNumbers,omitempty"`
+	Author            string   `json:"author" yaml:"author"`
+	Title             string   `json:"title,omitempty" yaml:"title,omitempty"`
+	ReleaseLabel      string   `json:"releaseLabel" yaml:"releaseLabel"`
+	Labels            []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	BackportBranches  []string `json:"backportBranches,omitempty" yaml:"backportBranches,omitempty"`
+	// ReleaseNote is Markdown, which may span several lines, e.g. for
+	// nested lists or code blocks.
+	ReleaseNote string `json:"releaseNote" yaml:"releaseNote"`
+	// Reverts lists the PRs, upstream PRs for backports, reverted by the
+	// entry.
+	Reverts []int `json:"reverts,omitempty" yaml:"reverts,omitempty"`
+	// UpgradeNotes is the, possibly multi-line, Markdown that users need to
+	// read before upgrading.
+	UpgradeNotes string `json:"upgradeNotes,omitempty" yaml:"upgradeNotes,omitempty"`
+	// Embargoed is set if the release note was replaced by a placeholder.
+	// The release notes then don't reference the PRs and author of the
+	// entry.
+	Embargoed bool `json:"embargoed,omitempty" yaml:"embargoed,omitempty"`
+}
+
+// IsBackport returns true if the entry was merged through a backport PR.
+func (e Entry) IsBackport() bool {
+	return e.UpstreamPRNumber != 0
+}
+
+// backportPRs returns all backport PRs of a backport entry.
+func (e Entry) backportPRs() []int {
+	if len(e.BackportPRNumbers) == 0 {
+		return []int{e.PRNumber}
+	}
+	return e.BackportPRNumbers
+}
+
+func newEntry(pr types.PullRequest, prNumber, upstreamPRNumber int) Entry {
+	return Entry{
+		PRNumber:         prNumber,
+		UpstreamPRNumber: upstreamPRNumber,
+		Author:           pr.AuthorName,
+		Title:            pr.Title,
+		ReleaseLabel:     pr.ReleaseLabel,
+		Labels:           pr.Labels,
+		BackportBranches: pr.BackportBranches,
+		ReleaseNote:      pr.ReleaseNote,
+		Reverts:          pr.Reverts,
+		UpgradeNotes:     pr.UpgradeNotes,
+	}
+}
+
+// split returns one entry per top-level item of the release note if it is a
+// Markdown list, e.g. when a PR has several user-facing changes, or e
+// otherwise. List markers are removed since every entry is a bullet already.
+func (e Entry) split() []Entry {
+	items := splitReleaseNote(e.ReleaseNote)
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		entry := e
+		entry.ReleaseNote = item
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// splitReleaseNote returns the top-level "-" and "*" items of note, along with
+// the lines nested in them, or note if it doesn't start with such an item.
+func splitReleaseNote(note string) []string {
+	lines := strings.Split(note, "\n")
+	if !isTopLevelItem(lines[0]) {
+		return []string{note}
+	}
+	var items []string
+	var item []string
+	flush := func() {
+		if len(item) != 0 {
+			items = append(items, strings.Trim(strings.Join(item, "\n"), "\n"))
+		}
+	}
+	for _, line := range lines {
+		if isTopLevelItem(line) {
+			flush()
+			item = []string{strings.TrimSpace(line[2:])}
+			continue
+		}
+		// Nested lines are indented by the width of the list marker.
+		item = append(item, strings.TrimPrefix(line, "  "))
+	}
+	flush()
+	return items
+}
+
+func isTopLevelItem(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+// releaseNotesOrder returns the release-note labels that should be part of
+// the release notes, in the order they should be printed.
+func (cl *ChangeLog) releaseNotesOrder() []string {
+	if len(cl.ReleaseLabels) == 0 {
+		return defaultReleaseNotesOrder
+	}
+	// Only add release notes for release labels specified by --release-labels
+	var releaseNotesOrder []string
+	for _, label := range defaultReleaseNotesOrder {
+		if !slices.Contains(cl.ReleaseLabels, label) {
+			continue
+		}
+		releaseNotesOrder = append(releaseNotesOrder, label)
+	}
+	return releaseNotesOrder
+}
+
+// filteredPRs returns a copy of the PRs and backport PRs after applying
+// --label-filter and --exclude-labels.
+func (cl *ChangeLog) filteredPRs() (types.PullRequests, types.BackportPRs) {
+	var (
+		listOfPRs       = make(types.PullRequests)
+		prsWithUpstream = make(types.BackportPRs)
+	)
+
+	// Filter the PRs by --label-filter and --exclude-labels
+	for id, pr := range cl.listOfPrs.DeepCopy() {
+		if !filterByLabels(pr.Labels, cl.LabelFilters) {
+			continue
+		}
+		if len(cl.ExcludeLabels) > 0 && filterByLabels(pr.Labels, cl.ExcludeLabels) {
+			continue
+		}
+		listOfPRs[id] = pr
+	}
+
+	// Filter the Backport PRs by --label-filter and --exclude-labels
+	for prNumber, upstreamedPRs := range cl.prsWithUpstream.DeepCopy() {
+		for upstreamPRNumber, upstreamPR := range upstreamedPRs {
+			if !filterByLabels(upstreamPR.Labels, cl.LabelFilters) {
+				continue
+			}
+			if len(cl.ExcludeLabels) > 0 && filterByLabels(upstreamPR.Labels, cl.ExcludeLabels) {
+				continue
+			}
+			if prsWithUpstream[prNumber] == nil {
+				prsWithUpstream[prNumber] = make(types.PullRequests)
+			}
+			prsWithUpstream[prNumber][upstreamPRNumber] = upstreamPR
+		}
+	}
+	return listOfPRs, prsWithUpstream
+}
+
+// isBackportedToLastStable returns true if the PR was already backported to
+// the branch set in --last-stable.
+func (cl *ChangeLog) isBackportedToLastStable(pr types.PullRequest) bool {
+	if len(cl.LastStable) == 0 {
+		return false
+	}
+	for _, bb := range pr.BackportBranches {
+		if strings.Contains(bb, cl.LastStable) {
+			return true
+		}
+	}
+	return false
+}
+
+// Model builds the structured release notes from the PRs retrieved by
+// GenerateReleaseNotes.
+func (cl *ChangeLog) Model() *Model {
+	listOfPRs, prsWithUpstream := cl.filteredPRs()
+
+	cl.Logger.Printf("Found %d PRs and %d backport PRs in %s\n\n", len(listOfPRs), len(prsWithUpstream), cl.StateFile)
+
+	m := &Model{
+		Repository: cl.RepoName,
+		Base:       cl.Base,
+		Head:       cl.Head,
+		Version:    cl.TargetVer,
+		Date:       cl.ReleaseDate,
+
+		SecurityAdvisories: cl.advisories,
+		GoModules:          cl.moduleChanges,
+	}
+	if len(m.Date) == 0 {
+		m.Date = time.Now().Format(time.DateOnly)
+	}
+	releaseNotesOrder := cl.releaseNotesOrder()
+	alreadyReleased := map[string][]Entry{}
+	allEntries := backportEntries(prsWithUpstream)
+	for _, prID := range slices.Sorted(maps.Keys(listOfPRs)) {
+		allEntries = append(allEntries, newEntry(listOfPRs[prID], prID, 0))
+	}
+	allEntries, m.Reverted, m.UnresolvedReverts = dropReverts(cl.redactEmbargoed(allEntries))
+	isDependencyUpdate := cl.dependencyMatcher()
+
+	for _, releaseLabel := range releaseNotesOrder {
+		var entries []Entry
+		for _, e := range allEntries {
+			if e.ReleaseLabel != releaseLabel {
+				continue
+			}
+			if !e.IsBackport() && cl.isBackportedToLastStable(listOfPRs[e.PRNumber]) {
+				alreadyReleased[releaseLabel] = append(alreadyReleased[releaseLabel], e.split()...)
+				continue
+			}
+			if len(e.UpgradeNotes) != 0 {
+				m.UpgradeNotes = append(m.UpgradeNotes, e)
+			}
+			if isDependencyUpdate != nil && isDependencyUpdate(e) {
+				m.DependencyUpdates = append(m.DependencyUpdates, newDependencyUpdate(e))
+				continue
+			}
+			entries = append(entries, e.split()...)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		m.Sections = append(m.Sections, cl.newSection(releaseLabel, entries))
+	}
+
+	sort.Slice(m.UpgradeNotes, func(i, j int) bool {
+		return entryKey(m.UpgradeNotes[i]) < entryKey(m.UpgradeNotes[j])
+	})
+	sortDependencyUpdates(m.DependencyUpdates)
+
+	for _, releaseLabel := range releaseNotesOrder {
+		entries := alreadyReleased[releaseLabel]
+		if len(entries) == 0 {
+			continue
+		}
+		m.AlreadyReleased = append(m.AlreadyReleased, cl.newSection(releaseLabel, entries))
+	}
+
+	if cl.NewContributors {
+		m.NewContributors = cl.newContributors(allEntries, listOfPRs)
+	}
+
+	return m
+}
+
+// backportEntries returns one entry per upstream PR of the given backport PRs,
+// sorted by upstream PR number. An upstream PR that is part of several
+// backport PRs, e.g. because it was backported in two batches, has a single
+// entry referencing all of them.
+func backportEntries(prsWithUpstream types.BackportPRs) []Entry {
+	entries := map[int]*Entry{}
+	for _, backportPR := range slices.Sorted(maps.Keys(prsWithUpstream)) {
+		for upstreamPRNumber, pr := range prsWithUpstream[backportPR] {
+			if e, ok := entries[upstreamPRNumber]; ok {
+				if len(e.BackportPRNumbers) == 0 {
+					e.BackportPRNumbers = []int{e.PRNumber}
+				}
+				e.BackportPRNumbers = append(e.BackportPRNumbers, backportPR)
+				continue
+			}
+			e := newEntry(pr, backportPR, upstreamPRNumber)
+			entries[upstreamPRNumber] = &e
+		}
+	}
+
+	sorted := make([]Entry, 0, len(entries))
+	for _, upstreamPRNumber := range slices.Sorted(maps.Keys(entries)) {
+		sorted = append(sorted, *entries[upstreamPRNumber])
+	}
+	return sorted
+}
+
+// dropReverts removes from entries the pairs of a PR and its revert. Entries
+// are processed from the most recent one so that a revert of a revert drops
+// the first revert and keeps the original PR. Reverts of backported commits
+// reference the upstream PR, or the backport PR if the upstream commit could
+// not be resolved: its entry is only dropped if it is the only entry of the
+// backport PR. Otherwise nothing is dropped and the revert is returned as
+// unresolved.
+func dropReverts(entries []Entry) ([]Entry, []Revert, []Entry) {
+	byKey := map[int]int{}
+	byBackportPR := map[int][]int{}
+	for i, e := range entries {
+		byKey[entryKey(e)] = i
+		if !e.IsBackport() {
+			continue
+		}
+		for _, prNumber := range e.backportPRs() {
+			byBackportPR[prNumber] = append(byBackportPR[prNumber], i)
+		}
+	}
+	order := slices.SortedFunc(maps.Values(byKey), func(i, j int) int {
+		return entryKey(entries[j]) - entryKey(entries[i])
+	})
+
+	dropped := map[int]bool{}
+	var (
+		reverts    []Revert
+		unresolved []Entry
+	)
+	for _, i := range order {
+		if dropped[i] {
+			continue
+		}
+		for _, revertedPRNumber := range entries[i].Reverts {
+			j, ok := byKey[revertedPRNumber]
+			if !ok {
+				backportEntries := byBackportPR[revertedPRNumber]
+				if len(backportEntries) > 1 {
+					unresolved = append(unresolved, entries[i])
+					break
+				}
+				if len(backportEntries) == 0 {
+					continue
+				}
+				j = backportEntries[0]
+			}
+			if i == j || dropped[j] {
+				continue
+			}
+			dropped[i], dropped[j] = true, true
+			reverts = append(reverts, Revert{Entry: entries[j], RevertedBy: entries[i]})
+			break
+		}
+	}
+	sort.Slice(unresolved, func(i, j int) bool {
+		return entryKey(unresolved[i]) < entryKey(unresolved[j])
+	})
+	if len(reverts) == 0 {
+		return entries, nil, unresolved
+	}
+
+	kept := make([]Entry, 0, len(entries)-len(dropped))
+	for i, e := range entries {
+		if !dropped[i] {
+			kept = append(kept, e)
+		}
+	}
+	sort.Slice(reverts, func(i, j int) bool {
+		return entryKey(reverts[i].Entry) < entryKey(reverts[j].Entry)
+	})
+	return kept, reverts, unresolved
+}
+
+// duplicateUpstreamPRs returns, for each upstream PR that is part of more
+// than one backport PR, the backport PRs that include it.
+func duplicateUpstreamPRs(prsWithUpstream types.BackportPRs) map[int][]int {
+	duplicates := map[int][]int{}
+	for _, e := range backportEntries(prsWithUpstream) {
+		if len(e.BackportPRNumbers) != 0 {
+			duplicates[e.UpstreamPRNumber] = e.BackportPRNumbers
+		}
+	}
+	return duplicates
+}
+
+// newContributors returns the authors whose first merged PR is one of the
+// given entries, sorted by author. The entries are the ones left once the
+// embargoed PRs are redacted and the reverted PRs dropped, so that neither
+// shows up. Like their release notes, the placeholders of embargoed PRs don't
+// reference their author. PRs left out of the release notes because they
+// were already released are ignored.
+func (cl *ChangeLog) newContributors(entries []Entry, listOfPRs types.PullRequests) []Contributor {
+	var contributors []Contributor
+	for _, e := range entries {
+		prNumber := entryKey(e)
+		if firstPR, ok := cl.firstPRs[e.Author]; !ok || firstPR != prNumber {
+			continue
+		}
+		if !e.IsBackport() && cl.isBackportedToLastStable(listOfPRs[e.PRNumber]) {
+			continue
+		}
+		if e.Embargoed {
+			continue
+		}
+		contributors = append(contributors, Contributor{Author: e.Author, PRNumber: prNumber})
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		return strings.ToLower(contributors[i].Author) < strings.ToLower(contributors[j].Author)
+	})
+	return contributors
+}
+
+func (cl *ChangeLog) newSection(releaseLabel string, entries []Entry) Section {
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(cl.prReleaseNote(entries[i])) < strings.ToLower(cl.prReleaseNote(entries[j]))
+	})
+	return Section{
+		Label:   releaseLabel,
+		Title:   releaseNoteTitles[releaseLabel],
+		Entries: entries,
+		Groups:  cl.groupEntries(entries),
+	}
+}
+
+// groupEntries splits the entries by their first label matching
+// --group-by-label-prefix, keeping their order within each group. Groups are
+// sorted by title, followed by the group of entries without such label.
+func (cl *ChangeLog) groupEntries(entries []Entry) []Group {
+	if len(cl.GroupByLabelPrefix) == 0 {
+		return nil
+	}
+
+	groups := map[string]*Group{}
+	other := Group{Title: otherGroupTitle}
+	for _, e := range entries {
+		idx := slices.IndexFunc(e.Labels, func(lbl string) bool {
+			return strings.HasPrefix(lbl, cl.GroupByLabelPrefix)
+		})
+		if idx == -1 {
+			other.Entries = append(other.Entries, e)
+			continue
+		}
+		label := e.Labels[idx]
+		if groups[label] == nil {
+			title, ok := cl.GroupTitles[label]
+			if !ok {
+				title = strings.TrimPrefix(label, cl.GroupByLabelPrefix)
+			}
+			groups[label] = &Group{Label: label, Title: title}
+		}
+		groups[label].Entries = append(groups[label].Entries, e)
+	}
+
+	sorted := make([]Group, 0, len(groups)+1)
+	for _, g := range groups {
+		sorted = append(sorted, *g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Title) < strings.ToLower(sorted[j].Title)
+	})
+	if len(other.Entries) != 0 {
+		sorted = append(sorted, other)
+	}
+	return sorted
+}
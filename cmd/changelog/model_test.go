@@ -0,0 +1,634 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/deps"
+	"github.com/cilium/release/pkg/github"
+	"github.com/cilium/release/pkg/types"
+)
+
+func testChangeLog() *ChangeLog {
+	return &ChangeLog{
+		ChangeLogConfig: ChangeLogConfig{
+			CommonConfig: types.CommonConfig{RepoName: "cilium/cilium"},
+			Base:         "v1.16.0",
+			Head:         "v1.16.1",
+			LastStable:   "1.15",
+			TargetVer:    "v1.16.1",
+			ReleaseDate:  "2024-08-15",
+		},
+		Logger: log.New(io.Discard, "", 0),
+		prsWithUpstream: types.BackportPRs{
+			10: {
+				1: {
+					ReleaseNote:  "Fix a crash",
+					ReleaseLabel: "release-note/bug",
+					AuthorName:   "alice",
+					Labels:       []string{"release-note/bug"},
+				},
+			},
+		},
+		listOfPrs: types.PullRequests{
+			2: {
+				ReleaseNote:  "Add a feature",
+				ReleaseLabel: "release-note/minor",
+				AuthorName:   "bob",
+				Labels:       []string{"release-note/minor"},
+			},
+			3: {
+				ReleaseNote:      "Already released fix",
+				ReleaseLabel:     "release-note/bug",
+				AuthorName:       "carol",
+				Labels:           []string{"release-note/bug", "backport-done/1.15"},
+				BackportBranches: []string{"backport-done/1.15"},
+			},
+		},
+	}
+}
+
+func TestChangeLog_Model(t *testing.T) {
+	m := testChangeLog().Model()
+
+	assert.Equal(t, &Model{
+		Repository: "cilium/cilium",
+		Base:       "v1.16.0",
+		Head:       "v1.16.1",
+		Version:    "v1.16.1",
+		Date:       "2024-08-15",
+		Sections: []Section{
+			{
+				Label: "release-note/minor",
+				Title: "Minor Changes",
+				Entries: []Entry{{
+					PRNumber:     2,
+					Author:       "bob",
+					ReleaseLabel: "release-note/minor",
+					Labels:       []string{"release-note/minor"},
+					ReleaseNote:  "Add a feature",
+				}},
+			},
+			{
+				Label: "release-note/bug",
+				Title: "Bugfixes",
+				Entries: []Entry{{
+					PRNumber:         10,
+					UpstreamPRNumber: 1,
+					Author:           "alice",
+					ReleaseLabel:     "release-note/bug",
+					Labels:           []string{"release-note/bug"},
+					ReleaseNote:      "Fix a crash",
+				}},
+			},
+		},
+		AlreadyReleased: []Section{
+			{
+				Label: "release-note/bug",
+				Title: "Bugfixes",
+				Entries: []Entry{{
+					PRNumber:         3,
+					Author:           "carol",
+					ReleaseLabel:     "release-note/bug",
+					Labels:           []string{"release-note/bug", "backport-done/1.15"},
+					BackportBranches: []string{"backport-done/1.15"},
+					ReleaseNote:      "Already released fix",
+				}},
+			},
+		},
+	}, m)
+}
+
+func TestChangeLog_PrintReleaseNotesAs(t *testing.T) {
+	cl := testChangeLog()
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, `Summary of Changes
+------------------
+
+**Minor Changes:**
+* Add a feature (cilium/cilium#2, @bob)
+
+**Bugfixes:**
+* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputJSON))
+	var m Model
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, cl.Model(), &m)
+
+	assert.Error(t, cl.PrintReleaseNotesAs(&buf, "xml"))
+}
+
+func TestChangeLog_PrintReleaseNotesForWriter(t *testing.T) {
+	cl := testChangeLog()
+	cl.SkipHeader = true
+	cl.ExcludePRReferences = true
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesForWriter(&buf))
+	assert.Equal(t, `
+**Minor Changes:**
+* Add a feature
+
+**Bugfixes:**
+* Fix a crash
+`, buf.String())
+
+	tmpl := filepath.Join(t.TempDir(), "notes.tmpl")
+	err := os.WriteFile(tmpl, []byte(`# {{ .Version }} ({{ .Date }})
+{{ range .Sections }}{{ range .Entries }}- {{ .ReleaseNote }} [{{ .PRNumber }}{{ if .IsBackport }}/{{ .UpstreamPRNumber }}{{ end }}] {{ .Author }}
+{{ end }}{{ end }}`), 0644)
+	assert.NoError(t, err)
+
+	cl.TemplateFile = tmpl
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesForWriter(&buf))
+	assert.Equal(t, `# v1.16.1 (2024-08-15)
+- Add a feature [2] bob
+- Fix a crash [10/1] alice
+`, buf.String())
+}
+
+func TestChangeLog_GroupByLabelPrefix(t *testing.T) {
+	cl := testChangeLog()
+	cl.GroupByLabelPrefix = "area/"
+	cl.GroupTitles = map[string]string{"area/datapath": "Datapath"}
+	cl.listOfPrs[4] = types.PullRequest{
+		ReleaseNote:  "Add a datapath feature",
+		ReleaseLabel: "release-note/minor",
+		AuthorName:   "dave",
+		Labels:       []string{"release-note/minor", "area/datapath"},
+	}
+	cl.listOfPrs[5] = types.PullRequest{
+		ReleaseNote:  "Add a CLI feature",
+		ReleaseLabel: "release-note/minor",
+		AuthorName:   "erin",
+		Labels:       []string{"area/cli", "release-note/minor"},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, `Summary of Changes
+------------------
+
+**Minor Changes:**
+*cli:*
+* Add a CLI feature (cilium/cilium#5, @erin)
+
+*Datapath:*
+* Add a datapath feature (cilium/cilium#4, @dave)
+
+*Other:*
+* Add a feature (cilium/cilium#2, @bob)
+
+**Bugfixes:**
+*Other:*
+* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, `Summary of Changes
+==================
+
+Minor Changes
+-------------
+
+cli
+~~~
+
+* Add a CLI feature (:gh-pull:`+"`5`"+`, @erin)
+
+Datapath
+~~~~~~~~
+
+* Add a datapath feature (:gh-pull:`+"`4`"+`, @dave)
+
+Other
+~~~~~
+
+* Add a feature (:gh-pull:`+"`2`"+`, @bob)
+
+Bugfixes
+--------
+
+Other
+~~~~~
+
+* Fix a crash (Backport PR :gh-pull:`+"`10`"+`, Upstream PR :gh-pull:`+"`1`"+`, @alice)
+`, buf.String())
+}
+
+func TestChangeLog_NewContributors(t *testing.T) {
+	cl := testChangeLog()
+	cl.NewContributors = true
+	cl.firstPRs = map[string]int{
+		"alice": 1,
+		"bob":   1234,
+		// carol's first PR was already released.
+		"carol": 3,
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, `Summary of Changes
+------------------
+
+**Minor Changes:**
+* Add a feature (cilium/cilium#2, @bob)
+
+**Bugfixes:**
+* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+
+**New Contributors:**
+* @alice made their first contribution in cilium/cilium#1
+`, buf.String())
+
+	cl.ExcludePRReferences = true
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.NotContains(t, buf.String(), "New Contributors")
+}
+
+func TestChangeLog_SecurityAdvisories(t *testing.T) {
+	cl := testChangeLog()
+	cl.advisories = []github.Advisory{{
+		GHSAID:   "GHSA-bbbb",
+		CVEID:    "CVE-2024-2",
+		Severity: "high",
+		Summary:  "Policy bypass",
+		URL:      "https://github.com/cilium/cilium/security/advisories/GHSA-bbbb",
+	}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, `Summary of Changes
+------------------
+
+**Security Advisories:**
+* [GHSA-bbbb](https://github.com/cilium/cilium/security/advisories/GHSA-bbbb) (CVE-2024-2, severity: high): Policy bypass
+
+**Minor Changes:**
+* Add a feature (cilium/cilium#2, @bob)
+
+**Bugfixes:**
+* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+`, buf.String())
+
+	cl.SkipHeader = true
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, `Security Advisories
+-------------------
+
+* `+"`GHSA-bbbb <https://github.com/cilium/cilium/security/advisories/GHSA-bbbb>`__"+` (CVE-2024-2, severity: high): Policy bypass
+
+Minor Changes
+-------------
+
+* Add a feature (:gh-pull:`+"`2`"+`, @bob)
+
+Bugfixes
+--------
+
+* Fix a crash (Backport PR :gh-pull:`+"`10`"+`, Upstream PR :gh-pull:`+"`1`"+`, @alice)
+`, buf.String())
+}
+
+func TestChangeLog_DuplicateUpstreamPRs(t *testing.T) {
+	cl := testChangeLog()
+	// PR 1 was backported again in a second batch.
+	cl.prsWithUpstream[12] = types.PullRequests{1: cl.prsWithUpstream[10][1]}
+
+	m := cl.Model()
+	assert.Equal(t, []Entry{{
+		PRNumber:          10,
+		UpstreamPRNumber:  1,
+		BackportPRNumbers: []int{10, 12},
+		Author:            "alice",
+		ReleaseLabel:      "release-note/bug",
+		Labels:            []string{"release-note/bug"},
+		ReleaseNote:       "Fix a crash",
+	}}, m.Sections[1].Entries)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Contains(t, buf.String(), "\n**Bugfixes:**\n* Fix a crash (Backport PRs cilium/cilium#10, cilium/cilium#12, Upstream PR cilium/cilium#1, @alice)\n")
+
+	buf.Reset()
+	cl.SkipHeader = true
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Contains(t, buf.String(), "* Fix a crash (Backport PRs :gh-pull:`10`, :gh-pull:`12`, Upstream PR :gh-pull:`1`, @alice)\n")
+
+	prNumbers, backportPRNumbers := cl.lintTargets()
+	assert.Equal(t, []int{1, 2, 3}, prNumbers)
+	assert.Equal(t, []int{10, 12}, backportPRNumbers)
+
+	results := cl.addDuplicateUpstreamIssues([]github.LintResult{{PRNumber: 12, Title: "v1.16 backports"}, {PRNumber: 2}})
+	assert.Equal(t, []github.LintResult{
+		{PRNumber: 2},
+		{
+			PRNumber: 10,
+			Issues: []github.LintIssue{{
+				Severity: github.LintWarning,
+				Rule:     "duplicate-upstream-pr",
+				Message:  "upstream PR #1 is also part of backport PR #12, it is listed once in the release notes",
+			}},
+		},
+		{
+			PRNumber: 12,
+			Title:    "v1.16 backports",
+			Issues: []github.LintIssue{{
+				Severity: github.LintWarning,
+				Rule:     "duplicate-upstream-pr",
+				Message:  "upstream PR #1 is also part of backport PR #10, it is listed once in the release notes",
+			}},
+		},
+	}, results)
+}
+
+func TestChangeLog_Reverts(t *testing.T) {
+	cl := testChangeLog()
+	var logs bytes.Buffer
+	cl.Logger = log.New(&logs, "", 0)
+	cl.listOfPrs[4] = types.PullRequest{
+		ReleaseNote:  `Revert "Add a feature"`,
+		ReleaseLabel: "release-note/misc",
+		AuthorName:   "bob",
+		Reverts:      []int{2},
+	}
+	// A revert of the upstream PR 1 reverted again, PR 1 is kept.
+	cl.listOfPrs[5] = types.PullRequest{
+		ReleaseNote:  `Revert "Fix a crash"`,
+		ReleaseLabel: "release-note/bug",
+		AuthorName:   "alice",
+		Reverts:      []int{1},
+	}
+	cl.listOfPrs[6] = types.PullRequest{
+		ReleaseNote:  `Revert "Revert "Fix a crash""`,
+		ReleaseLabel: "release-note/bug",
+		AuthorName:   "alice",
+		Reverts:      []int{5},
+	}
+
+	m := cl.Model()
+	assert.Equal(t, []Revert{
+		{
+			Entry:      newEntry(cl.listOfPrs[2], 2, 0),
+			RevertedBy: newEntry(cl.listOfPrs[4], 4, 0),
+		},
+		{
+			Entry:      newEntry(cl.listOfPrs[5], 5, 0),
+			RevertedBy: newEntry(cl.listOfPrs[6], 6, 0),
+		},
+	}, m.Reverted)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, `Summary of Changes
+------------------
+
+**Bugfixes:**
+* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+`, buf.String())
+	assert.Contains(t, logs.String(), `**Reverted:**
+* Add a feature (cilium/cilium#2, @bob)
+  reverted by Revert "Add a feature" (cilium/cilium#4, @bob)
+* Revert "Fix a crash" (cilium/cilium#5, @alice)
+  reverted by Revert "Revert "Fix a crash"" (cilium/cilium#6, @alice)
+`)
+}
+
+func TestChangeLog_RevertedBackport(t *testing.T) {
+	cl := testChangeLog()
+	// The revert of the commit backported by PR 10 references the backport
+	// PR instead of the upstream PR 1.
+	cl.listOfPrs[4] = types.PullRequest{
+		ReleaseNote:  `Revert "Fix a crash"`,
+		ReleaseLabel: "release-note/bug",
+		AuthorName:   "alice",
+		Reverts:      []int{10},
+	}
+
+	m := cl.Model()
+	assert.Equal(t, []Revert{
+		{
+			Entry:      newEntry(cl.prsWithUpstream[10][1], 10, 1),
+			RevertedBy: newEntry(cl.listOfPrs[4], 4, 0),
+		},
+	}, m.Reverted)
+	for _, section := range m.Sections {
+		assert.NotEqual(t, "release-note/bug", section.Label)
+	}
+}
+
+func TestChangeLog_RevertedMultiUpstreamBackport(t *testing.T) {
+	cl := testChangeLog()
+	var logs bytes.Buffer
+	cl.Logger = log.New(&logs, "", 0)
+	cl.prsWithUpstream[10][5] = types.PullRequest{
+		ReleaseNote:  "Add a flag",
+		ReleaseLabel: "release-note/minor",
+		AuthorName:   "carol",
+	}
+	// The revert of a commit backported by PR 10, resolved to its upstream
+	// PR 5 through the upstream commit trailer.
+	cl.listOfPrs[6] = types.PullRequest{
+		ReleaseNote:  `Revert "Add a flag"`,
+		ReleaseLabel: "release-note/minor",
+		AuthorName:   "carol",
+		Reverts:      []int{5},
+	}
+	// The revert of a commit that could not be resolved to its upstream PR
+	// only references PR 10, whose entries are all kept.
+	cl.listOfPrs[7] = types.PullRequest{
+		ReleaseNote:  `Revert "Fix a crash"`,
+		ReleaseLabel: "release-note/bug",
+		AuthorName:   "alice",
+		Reverts:      []int{10},
+	}
+
+	m := cl.Model()
+	assert.Equal(t, []Revert{
+		{
+			Entry:      newEntry(cl.prsWithUpstream[10][5], 10, 5),
+			RevertedBy: newEntry(cl.listOfPrs[6], 6, 0),
+		},
+	}, m.Reverted)
+	assert.Equal(t, []Entry{newEntry(cl.listOfPrs[7], 7, 0)}, m.UnresolvedReverts)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, `Summary of Changes
+------------------
+
+**Minor Changes:**
+* Add a feature (cilium/cilium#2, @bob)
+
+**Bugfixes:**
+* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+* Revert "Fix a crash" (cilium/cilium#7, @alice)
+`, buf.String())
+	assert.Contains(t, logs.String(), `**Unresolved reverts:**
+* Revert "Fix a crash" (cilium/cilium#7, @alice)
+`)
+}
+
+func TestChangeLog_UpgradeNotes(t *testing.T) {
+	cl := testChangeLog()
+	pr := cl.listOfPrs[2]
+	pr.UpgradeNotes = "The `--foo` flag was removed:\n\n* use `--bar` instead\n\n```\nhelm upgrade --set bar=true\n```"
+	cl.listOfPrs[2] = pr
+	// Already released PRs are left out of the upgrade notes too.
+	pr = cl.listOfPrs[3]
+	pr.UpgradeNotes = "Already released"
+	cl.listOfPrs[3] = pr
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, "Summary of Changes\n"+
+		"------------------\n"+
+		"\n"+
+		"**Upgrade Notes:**\n"+
+		"* The `--foo` flag was removed: (cilium/cilium#2, @bob)\n"+
+		"\n"+
+		"  * use `--bar` instead\n"+
+		"\n"+
+		"  ```\n"+
+		"  helm upgrade --set bar=true\n"+
+		"  ```\n"+
+		"\n"+
+		"**Minor Changes:**\n"+
+		"* Add a feature (cilium/cilium#2, @bob)\n"+
+		"\n"+
+		"**Bugfixes:**\n"+
+		"* Fix a crash (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)\n", buf.String())
+
+	cl.SkipHeader = true
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, "Upgrade Notes\n"+
+		"-------------\n"+
+		"\n"+
+		"* The ``--foo`` flag was removed: (:gh-pull:`2`, @bob)\n"+
+		"\n"+
+		"  * use ``--bar`` instead\n"+
+		"\n"+
+		"  ::\n"+
+		"\n"+
+		"     helm upgrade --set bar=true\n"+
+		"\n"+
+		"Minor Changes\n"+
+		"-------------\n"+
+		"\n"+
+		"* Add a feature (:gh-pull:`2`, @bob)\n"+
+		"\n"+
+		"Bugfixes\n"+
+		"--------\n"+
+		"\n"+
+		"* Fix a crash (Backport PR :gh-pull:`10`, Upstream PR :gh-pull:`1`, @alice)\n", buf.String())
+}
+
+func TestChangeLog_MultiLineReleaseNotes(t *testing.T) {
+	cl := testChangeLog()
+	pr := cl.listOfPrs[2]
+	pr.ReleaseNote = "- Add a feature\n- Add a command:\n  ~~~\n  cilium foo\n  ~~~"
+	cl.listOfPrs[2] = pr
+	pr = cl.prsWithUpstream[10][1]
+	pr.ReleaseNote = "Fix a crash of:\n- the agent\n- the operator"
+	cl.prsWithUpstream[10][1] = pr
+
+	m := cl.Model()
+	if assert.Len(t, m.Sections, 2) {
+		assert.Len(t, m.Sections[0].Entries, 2)
+		assert.Len(t, m.Sections[1].Entries, 1)
+	}
+	// The entries of the same PR are told apart.
+	assert.Empty(t, Diff(m, cl.Model(), cl.releaseNotesOrder()))
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, "Summary of Changes\n"+
+		"------------------\n"+
+		"\n"+
+		"**Minor Changes:**\n"+
+		"* Add a command: (cilium/cilium#2, @bob)\n"+
+		"  ~~~\n"+
+		"  cilium foo\n"+
+		"  ~~~\n"+
+		"* Add a feature (cilium/cilium#2, @bob)\n"+
+		"\n"+
+		"**Bugfixes:**\n"+
+		"* Fix a crash of: (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)\n"+
+		"  - the agent\n"+
+		"  - the operator\n", buf.String())
+
+	cl.SkipHeader = true
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, "Minor Changes\n"+
+		"-------------\n"+
+		"\n"+
+		"* Add a command: (:gh-pull:`2`, @bob)\n"+
+		"\n"+
+		"  ::\n"+
+		"\n"+
+		"     cilium foo\n"+
+		"\n"+
+		"* Add a feature (:gh-pull:`2`, @bob)\n"+
+		"\n"+
+		"Bugfixes\n"+
+		"--------\n"+
+		"\n"+
+		"* Fix a crash of: (Backport PR :gh-pull:`10`, Upstream PR :gh-pull:`1`, @alice)\n"+
+		"\n"+
+		"  - the agent\n"+
+		"  - the operator\n", buf.String())
+}
+
+func TestChangeLog_GoModules(t *testing.T) {
+	cl := testChangeLog()
+	cl.SkipHeader = true
+	cl.ReleaseLabels = []string{"release-note/minor"}
+	cl.moduleChanges = []deps.ModuleChange{
+		{Path: "github.com/cilium/ebpf", Change: deps.ChangeUpgraded, OldVersion: "v0.15.0", NewVersion: "v0.16.0"},
+		{Path: "gopkg.in/yaml.v2", NewPath: "gopkg.in/yaml.v3", Change: deps.ChangeUpgraded, OldVersion: "v2.4.0", NewVersion: "v3.0.1", Major: true},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputMarkdown))
+	assert.Equal(t, "\n"+
+		"**Minor Changes:**\n"+
+		"* Add a feature (cilium/cilium#2, @bob)\n"+
+		"\n"+
+		"**Go Module Changes:**\n"+
+		"* `github.com/cilium/ebpf` v0.15.0 -> v0.16.0\n"+
+		"* `gopkg.in/yaml.v2` v2.4.0 -> `gopkg.in/yaml.v3` v3.0.1 (major version upgrade)\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, "Minor Changes\n"+
+		"-------------\n"+
+		"\n"+
+		"* Add a feature (:gh-pull:`2`, @bob)\n"+
+		"\n"+
+		"Go Module Changes\n"+
+		"-----------------\n"+
+		"\n"+
+		"* ``github.com/cilium/ebpf`` v0.15.0 -> v0.16.0\n"+
+		"* ``gopkg.in/yaml.v2`` v2.4.0 -> ``gopkg.in/yaml.v3`` v3.0.1 (major version upgrade)\n", buf.String())
+}
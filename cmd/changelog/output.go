@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputMarkdown = "markdown"
+	OutputJSON     = "json"
+	OutputYAML     = "yaml"
+	OutputRST      = "rst"
+)
+
+// OutputFormats lists all formats accepted by --output.
+var OutputFormats = []string{OutputMarkdown, OutputJSON, OutputYAML, OutputRST}
+
+// PrintReleaseNotesAs writes the release notes into w in the given format,
+// then logs the PRs whose release notes were redacted.
+func (cl *ChangeLog) PrintReleaseNotesAs(w io.Writer, format string) error {
+	var err error
+	switch format {
+	case "", OutputMarkdown:
+		err = cl.PrintReleaseNotesForWriter(w)
+	case OutputRST:
+		err = cl.PrintReleaseNotesRST(w)
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(cl.Model())
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err = enc.Encode(cl.Model()); err == nil {
+			err = enc.Close()
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	cl.printEmbargoed()
+	return nil
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/release/pkg/github"
+)
+
+// PreviewConfig configures the preview subcommand.
+type PreviewConfig struct {
+	ChangeLogConfig
+
+	PRNumbers            []int
+	MaxReleaseNoteLength int
+}
+
+func (cfg *PreviewConfig) Sanitize() error {
+	if len(cfg.PRNumbers) == 0 {
+		return fmt.Errorf("--pr can't be empty\n")
+	}
+	if strings.Contains(cfg.LastStable, "v") {
+		return fmt.Errorf("--last-stable can't contain letters, should be of the format 'x.y'\n")
+	}
+	// No state file is used, so only the repository needs to be validated.
+	return cfg.CommonConfig.Sanitize()
+}
+
+// printPreview writes into w how the PR in preview will show up in the
+// release notes, followed by the issues found in its release note.
+func (cl *ChangeLog) printPreview(w io.Writer, preview github.PullRequestPreview) {
+	fmt.Fprintf(w, "%s#%d: %s\n", cl.RepoName, preview.Number, preview.Title)
+	if preview.State == "OPEN" {
+		fmt.Fprintf(w, "  NOTE: the PR is not merged yet\n")
+	}
+	printIssues := func(issues []github.LintIssue) {
+		for _, issue := range issues {
+			fmt.Fprintf(w, "  %s: %s: %s\n", issue.Severity, issue.Rule, issue.Message)
+		}
+	}
+	printEntry := func(e Entry, pr github.PullRequestPreview) {
+		title, ok := releaseNoteTitles[e.ReleaseLabel]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "  Section: none, %s is not a known release-note label\n", e.ReleaseLabel)
+		case !slices.Contains(cl.releaseNotesOrder(), e.ReleaseLabel):
+			fmt.Fprintf(w, "  Section: %s (%s), left out by --release-labels\n", title, e.ReleaseLabel)
+		default:
+			fmt.Fprintf(w, "  Section: %s (%s)\n", title, e.ReleaseLabel)
+		}
+		if cl.isBackportedToLastStable(pr.PullRequest) {
+			fmt.Fprintf(w, "  NOTE: already backported to %s, it is left out of the release notes\n", cl.LastStable)
+		}
+		for _, entry := range e.split() {
+			for _, line := range strings.Split(cl.prReleaseNote(entry), "\n") {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+		if len(e.UpgradeNotes) != 0 {
+			fmt.Fprintf(w, "  Upgrade Notes:\n")
+			for _, line := range strings.Split(e.UpgradeNotes, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+		printIssues(pr.Issues)
+	}
+
+	if !preview.Backport {
+		printEntry(newEntry(preview.PullRequest, preview.Number, 0), preview)
+		return
+	}
+	fmt.Fprintf(w, "  Backport PR, the release notes of its upstream PRs are used\n")
+	printIssues(preview.Issues)
+	for _, upstream := range preview.Upstream {
+		fmt.Fprintf(w, "\n  Upstream PR %s#%d: %s\n", cl.RepoName, upstream.Number, upstream.Title)
+		printEntry(newEntry(upstream.PullRequest, preview.Number, upstream.Number), upstream)
+	}
+}
+
+func previewCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
+	var cfg PreviewConfig
+
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Show how PRs will look like in the release notes",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			previews, err := github.PreviewPullRequests(ctx, github.NewGQLClient(), cfg.Owner, cfg.Repo, cfg.PRNumbers, github.LintOptions{
+				MaxReleaseNoteLength: cfg.MaxReleaseNoteLength,
+			})
+			if err != nil {
+				return err
+			}
+			cl := &ChangeLog{ChangeLogConfig: cfg.ChangeLogConfig, Logger: logger}
+			for i, preview := range previews {
+				if i != 0 {
+					fmt.Println()
+				}
+				cl.printPreview(os.Stdout, preview)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntSliceVar(&cfg.PRNumbers, "pr", nil, "Number of the PR to preview. Can be repeated")
+	cmd.Flags().StringVar(&cfg.RepoName, "repo", "cilium/cilium", "GitHub organization and repository names separated by a slash")
+	cmd.Flags().StringVar(&cfg.LastStable, "last-stable", "", "When last stable version is set, it will be used to detect if a bug was already backported or not to that particular branch (e.g.: '1.5', '1.6')")
+	cmd.Flags().StringArrayVar(&cfg.ReleaseLabels, "release-labels", []string{}, "Specify release labels to consider when generating the changelog. This also defines the order of the release notes.")
+	cmd.Flags().BoolVar(&cfg.ExcludePRReferences, "exclude-pr-references", false, "If true, do not include references to the PR or PR author")
+	cmd.Flags().IntVar(&cfg.MaxReleaseNoteLength, "max-length", github.DefaultMaxReleaseNoteLength, "Release notes longer than this number of characters are reported. 0 disables the check")
+
+	cobra.MarkFlagRequired(cmd.Flags(), "pr")
+	return cmd
+}
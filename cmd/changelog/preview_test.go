@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/github"
+	"github.com/cilium/release/pkg/types"
+)
+
+func TestChangeLog_printPreview(t *testing.T) {
+	cl := testChangeLog()
+
+	var buf bytes.Buffer
+	cl.printPreview(&buf, github.PullRequestPreview{
+		Number: 2,
+		Title:  "agent: Fix a crash",
+		State:  "OPEN",
+		PullRequest: types.PullRequest{
+			ReleaseNote:  "agent: Fix a crash",
+			ReleaseLabel: "release-note/bug",
+			AuthorName:   "bob",
+		},
+		Issues: []github.LintIssue{{
+			Severity: github.LintError,
+			Rule:     "missing-release-note",
+			Message:  "no ```release-note block, the PR title is used instead",
+		}},
+	})
+	assert.Equal(t, `cilium/cilium#2: agent: Fix a crash
+  NOTE: the PR is not merged yet
+  Section: Bugfixes (release-note/bug)
+  * agent: Fix a crash (cilium/cilium#2, @bob)
+  error: missing-release-note: no `+"```"+`release-note block, the PR title is used instead
+`, buf.String())
+
+	buf.Reset()
+	cl.printPreview(&buf, github.PullRequestPreview{
+		Number:   10,
+		Title:    "v1.16 backports",
+		State:    "MERGED",
+		Backport: true,
+		Issues: []github.LintIssue{{
+			Severity: github.LintError,
+			Rule:     "unknown-upstream-pr",
+			Message:  "the ```upstream-prs block references #3 which is not a PR, it is left out of the release notes",
+		}},
+		Upstream: []github.PullRequestPreview{{
+			Number: 1,
+			Title:  "Add a feature",
+			PullRequest: types.PullRequest{
+				ReleaseNote:  "Add a feature",
+				ReleaseLabel: "release-note/minor",
+				AuthorName:   "alice",
+			},
+		}},
+	})
+	assert.Equal(t, `cilium/cilium#10: v1.16 backports
+  Backport PR, the release notes of its upstream PRs are used
+  error: unknown-upstream-pr: the `+"```"+`upstream-prs block references #3 which is not a PR, it is left out of the release notes
+
+  Upstream PR cilium/cilium#1: Add a feature
+  Section: Minor Changes (release-note/minor)
+  * Add a feature (Backport PR cilium/cilium#10, Upstream PR cilium/cilium#1, @alice)
+`, buf.String())
+}
@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cilium/release/pkg/github"
+)
+
+const (
+	// RSTLinkRole renders PR references with the :gh-pull: role defined in
+	// the Sphinx configuration of the documentation.
+	RSTLinkRole = "role"
+	// RSTLinkURL renders PR references as full GitHub URLs.
+	RSTLinkURL = "url"
+)
+
+// RSTLinkStyles lists all styles accepted by --rst-link-style.
+var RSTLinkStyles = []string{RSTLinkRole, RSTLinkURL}
+
+// PrintReleaseNotesRST writes the release notes into w as reStructuredText so
+// that they can be included in the documentation tree.
+func (cl *ChangeLog) PrintReleaseNotesRST(w io.Writer) error {
+	m := cl.Model()
+
+	// Sections are separated by a blank line from whatever precedes them.
+	printed := !cl.SkipHeader
+	sectionTitle := func(title string) {
+		if printed {
+			fmt.Fprintln(w)
+		}
+		printed = true
+		fmt.Fprint(w, rstTitle(title, '-'))
+	}
+
+	if !cl.SkipHeader {
+		fmt.Fprint(w, rstTitle("Summary of Changes", '='))
+	}
+	if len(m.UpgradeNotes) != 0 {
+		sectionTitle("Upgrade Notes")
+		fmt.Fprintln(w)
+		var bullets []string
+		for _, entry := range m.UpgradeNotes {
+			bullets = append(bullets, cl.rstUpgradeNotes(entry))
+		}
+		printRSTBullets(w, bullets)
+	}
+	if len(m.SecurityAdvisories) != 0 {
+		sectionTitle("Security Advisories")
+		fmt.Fprintln(w)
+		for _, adv := range m.SecurityAdvisories {
+			fmt.Fprintln(w, rstAdvisory(adv))
+		}
+	}
+	for _, section := range m.Sections {
+		sectionTitle(section.Title)
+		for _, group := range section.EntryGroups() {
+			fmt.Fprintln(w)
+			if len(group.Title) != 0 {
+				fmt.Fprint(w, rstTitle(rstEscape(group.Title), '~'))
+				fmt.Fprintln(w)
+			}
+			var bullets []string
+			for _, entry := range group.Entries {
+				bullets = append(bullets, cl.rstReleaseNote(entry))
+			}
+			printRSTBullets(w, bullets)
+		}
+	}
+
+	if len(m.DependencyUpdates) != 0 {
+		sectionTitle("Dependency Updates")
+		fmt.Fprintln(w)
+		fmt.Fprint(w, cl.rstDependencyTable(m.DependencyUpdates))
+	}
+
+	if len(m.GoModules) != 0 {
+		sectionTitle("Go Module Changes")
+		fmt.Fprintln(w)
+		for _, c := range m.GoModules {
+			fmt.Fprintln(w, "* "+rstInline(strings.TrimPrefix(c.Markdown(), "* ")))
+		}
+	}
+
+	if len(m.NewContributors) != 0 && !cl.ExcludePRReferences {
+		sectionTitle("New Contributors")
+		fmt.Fprintln(w)
+		for _, c := range m.NewContributors {
+			fmt.Fprintf(w, "* @%s made their first contribution in %s\n", rstEscape(c.Author), cl.rstPRLink(c.PRNumber))
+		}
+	}
+
+	cl.printAlreadyReleased(m)
+	cl.printReverted(m)
+	cl.printUnresolvedReverts(m)
+	return nil
+}
+
+// printRSTBullets writes the bullets of a list into w. Bullets with nested
+// blocks are separated by a blank line from the bullets around them, as
+// required to end those blocks.
+func printRSTBullets(w io.Writer, bullets []string) {
+	for i, bullet := range bullets {
+		if i != 0 && (strings.Contains(bullet, "\n") || strings.Contains(bullets[i-1], "\n")) {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, bullet)
+	}
+}
+
+// rstAdvisory returns the reStructuredText bullet for a security advisory.
+func rstAdvisory(adv github.Advisory) string {
+	text := fmt.Sprintf("* `%s <%s>`__", adv.GHSAID, adv.URL)
+	var details []string
+	if len(adv.CVEID) != 0 {
+		details = append(details, adv.CVEID)
+	}
+	if len(adv.Severity) != 0 {
+		details = append(details, "severity: "+adv.Severity)
+	}
+	if len(details) != 0 {
+		text += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+	return text + ": " + rstInline(adv.Summary)
+}
+
+func rstTitle(title string, underline rune) string {
+	return fmt.Sprintf("%s\n%s\n", title, strings.Repeat(string(underline), utf8.RuneCountInString(title)))
+}
+
+// rstReleaseNote returns the reStructuredText bullet for a changelog entry.
+func (cl *ChangeLog) rstReleaseNote(e Entry) string {
+	return cl.rstMultiLineBullet(e.ReleaseNote, e)
+}
+
+// rstUpgradeNotes returns the reStructuredText bullet for the upgrade notes
+// of a changelog entry.
+func (cl *ChangeLog) rstUpgradeNotes(e Entry) string {
+	return cl.rstMultiLineBullet(e.UpgradeNotes, e)
+}
+
+// rstMultiLineBullet returns the reStructuredText bullet for the Markdown
+// text of e. The lines after the first one are nested in the bullet.
+func (cl *ChangeLog) rstMultiLineBullet(text string, e Entry) string {
+	first, rest, _ := strings.Cut(text, "\n")
+	bullet := cl.rstBullet(rstInline(first), e)
+	if len(strings.TrimSpace(rest)) != 0 {
+		bullet += "\n\n" + rstBlock(rest, "  ")
+	}
+	return bullet
+}
+
+// rstBullet returns a reStructuredText bullet with text followed by the
+// references to the PRs of e.
+func (cl *ChangeLog) rstBullet(text string, e Entry) string {
+	text = "* " + text
+	if !cl.ExcludePRReferences && !e.Embargoed {
+		text += fmt.Sprintf(" (%s)", cl.rstReferences(e))
+	}
+	return text
+}
+
+// rstReferences returns the references to the PRs and author of e.
+func (cl *ChangeLog) rstReferences(e Entry) string {
+	if e.IsBackport() {
+		return fmt.Sprintf("%s, Upstream PR %s, @%s", backportPRReferences(e, cl.rstPRLink), cl.rstPRLink(e.UpstreamPRNumber), rstEscape(e.Author))
+	}
+	return fmt.Sprintf("%s, @%s", cl.rstPRLink(e.PRNumber), rstEscape(e.Author))
+}
+
+// rstDependencyTable returns the reStructuredText list table of the
+// dependency updates.
+func (cl *ChangeLog) rstDependencyTable(updates []DependencyUpdate) string {
+	header := []string{"Dependency", "From", "To"}
+	if !cl.ExcludePRReferences {
+		header = append(header, "References")
+	}
+	rows := [][]string{header}
+	for _, du := range updates {
+		name := rstInline(du.ReleaseNote)
+		if len(du.Dependency) != 0 {
+			name = "``" + du.Dependency + "``"
+		}
+		row := []string{name, rstEscape(du.OldVersion), rstEscape(du.NewVersion)}
+		if !cl.ExcludePRReferences {
+			row = append(row, cl.rstReferences(du.Entry))
+		}
+		rows = append(rows, row)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(".. list-table::\n   :header-rows: 1\n\n")
+	for _, row := range rows {
+		for i, cell := range row {
+			marker := "     -"
+			if i == 0 {
+				marker = "   * -"
+			}
+			sb.WriteString(strings.TrimRight(marker+" "+strings.Join(strings.Fields(cell), " "), " ") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func (cl *ChangeLog) rstPRLink(prNumber int) string {
+	if cl.RSTLinkStyle == RSTLinkURL {
+		// Use anonymous hyperlinks (double underscore) to avoid duplicate
+		// target names when the same PR is referenced more than once.
+		return fmt.Sprintf("`%s#%d <https://github.com/%s/pull/%d>`__", cl.RepoName, prNumber, cl.RepoName, prNumber)
+	}
+	return fmt.Sprintf(":gh-pull:`%d`", prNumber)
+}
+
+// rstInline converts a single-line Markdown release note into
+// reStructuredText. Markdown code spans are converted into inline literals
+// and every other inline markup character is escaped.
+func rstInline(text string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '`')
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(text[start+1:], '`')
+		if end == -1 {
+			break
+		}
+		end += start + 1
+		sb.WriteString(rstEscape(text[:start]))
+		if literal := strings.TrimSpace(text[start+1 : end]); len(literal) != 0 {
+			sb.WriteString("``" + literal + "``")
+		}
+		text = text[end+1:]
+	}
+	sb.WriteString(rstEscape(text))
+	return sb.String()
+}
+
+// rstBlock converts multi-line Markdown into reStructuredText with every line
+// prefixed by indent. Lines are converted with rstInline, except for their
+// list markers and fenced code blocks, which are converted into literal
+// blocks.
+func rstBlock(text, indent string) string {
+	var lines []string
+	inCode := false
+	for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			if inCode {
+				lines = append(lines, "", indent+"::", "")
+			} else {
+				lines = append(lines, "")
+			}
+			continue
+		}
+		switch {
+		case len(strings.TrimSpace(line)) == 0:
+			lines = append(lines, "")
+		case inCode:
+			lines = append(lines, indent+"   "+line)
+		default:
+			// Keep the leading spaces and list markers, which are the
+			// same in both languages.
+			content := strings.TrimLeft(line, " ")
+			lead := line[:len(line)-len(content)]
+			for _, marker := range []string{"* ", "- "} {
+				if strings.HasPrefix(content, marker) {
+					lead, content = lead+marker, content[len(marker):]
+					break
+				}
+			}
+			lines = append(lines, indent+lead+rstInline(content))
+		}
+	}
+	// Collapse the blank lines added around literal blocks.
+	lines = slices.CompactFunc(lines, func(a, b string) bool {
+		return len(a) == 0 && len(b) == 0
+	})
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+var rstEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"`", "\\`",
+	"_", `\_`,
+	"|", `\|`,
+)
+
+// rstEscape escapes all reStructuredText inline markup characters in text.
+func rstEscape(text string) string {
+	return rstEscaper.Replace(text)
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rstInline(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "plain text",
+			in:   "Fix a crash in the agent",
+			want: "Fix a crash in the agent",
+		},
+		{
+			name: "inline markup is escaped",
+			in:   "Support *wildcard* in to_fqdns | matchName",
+			want: `Support \*wildcard\* in to\_fqdns \| matchName`,
+		},
+		{
+			name: "code spans become literals",
+			in:   "Add `--enable-foo_bar` flag and `bpf.foo` option",
+			want: "Add ``--enable-foo_bar`` flag and ``bpf.foo`` option",
+		},
+		{
+			name: "unbalanced backtick is escaped",
+			in:   "Quote ` character",
+			want: "Quote \\` character",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rstInline(tt.in))
+		})
+	}
+}
+
+func TestChangeLog_PrintReleaseNotesRST(t *testing.T) {
+	cl := testChangeLog()
+
+	var buf bytes.Buffer
+	assert.NoError(t, cl.PrintReleaseNotesAs(&buf, OutputRST))
+	assert.Equal(t, `Summary of Changes
+==================
+
+Minor Changes
+-------------
+
+* Add a feature (:gh-pull:`+"`2`"+`, @bob)
+
+Bugfixes
+--------
+
+* Fix a crash (Backport PR :gh-pull:`+"`10`"+`, Upstream PR :gh-pull:`+"`1`"+`, @alice)
+`, buf.String())
+
+	cl.SkipHeader = true
+	cl.RSTLinkStyle = RSTLinkURL
+	buf.Reset()
+	assert.NoError(t, cl.PrintReleaseNotesRST(&buf))
+	assert.Equal(t, `Minor Changes
+-------------
+
+* Add a feature (`+"`cilium/cilium#2 <https://github.com/cilium/cilium/pull/2>`__"+`, @bob)
+
+Bugfixes
+--------
+
+* Fix a crash (Backport PR `+"`cilium/cilium#10 <https://github.com/cilium/cilium/pull/10>`__"+`, Upstream PR `+"`cilium/cilium#1 <https://github.com/cilium/cilium/pull/1>`__"+`, @alice)
+`, buf.String())
+}
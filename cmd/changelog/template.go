@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package changelog
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// defaultTemplate renders the release notes in the Markdown format used by
+// the CHANGELOG.md files of Cilium.
+//
+//go:embed templates/release-notes.md.tmpl
+var defaultTemplate string
+
+// TemplateData is the data available to release notes templates.
+type TemplateData struct {
+	*Model
+
+	// SkipHeader is set by --skip-header.
+	SkipHeader bool
+	// ExcludePRReferences is set by --exclude-pr-references.
+	ExcludePRReferences bool
+}
+
+// References returns the references to the PRs and author of e, as printed
+// after its release note. It is empty with --exclude-pr-references and for
+// the placeholders of embargoed entries.
+func (d TemplateData) References(e Entry) string {
+	if d.ExcludePRReferences || e.Embargoed {
+		return ""
+	}
+	return markdownReferences(d.Repository, e)
+}
+
+var templateFuncs = template.FuncMap{
+	"join":       strings.Join,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimPrefix": strings.TrimPrefix,
+	"hasPrefix":  strings.HasPrefix,
+	"splitLines": func(s string) []string { return strings.Split(s, "\n") },
+	"tableCell":  markdownTableCell,
+}
+
+// releaseNotesTemplate returns the template set by --template, or the
+// embedded default template if none was given.
+func (cl *ChangeLog) releaseNotesTemplate() (*template.Template, error) {
+	name, text := "default", defaultTemplate
+	if len(cl.TemplateFile) != 0 {
+		data, err := os.ReadFile(cl.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read template: %w", err)
+		}
+		name, text = cl.TemplateFile, string(data)
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func (cl *ChangeLog) executeTemplate(w io.Writer, m *Model) error {
+	tmpl, err := cl.releaseNotesTemplate()
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, TemplateData{
+		Model:               m,
+		SkipHeader:          cl.SkipHeader,
+		ExcludePRReferences: cl.ExcludePRReferences,
+	})
+}
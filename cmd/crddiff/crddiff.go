@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package crddiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/release/cmd/diffcmd"
+	"github.com/cilium/release/pkg/crd"
+)
+
+// Config configures the crd-diff command.
+type Config struct {
+	diffcmd.Config
+
+	CRDDirectory   string
+	FailOnBreaking bool
+}
+
+func (cfg *Config) Sanitize() error {
+	if err := cfg.Config.Sanitize(); err != nil {
+		return err
+	}
+	if len(cfg.CRDDirectory) == 0 {
+		return fmt.Errorf("--crd-dir can't be empty\n")
+	}
+	return nil
+}
+
+// PrintChanges writes the breaking changes into w as Markdown.
+func PrintChanges(w io.Writer, changes []crd.Change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No breaking changes")
+		return
+	}
+	fmt.Fprintln(w, "**Breaking CRD changes:**")
+	for _, c := range changes {
+		fmt.Fprintln(w, c.Markdown())
+	}
+}
+
+func Command(ctx context.Context, logger *log.Logger) *cobra.Command {
+	var cfg Config
+
+	cmd := &cobra.Command{
+		Use:   "crd-diff",
+		Short: "Show the breaking changes of the CRD schemas between two refs",
+		Long: `Compares the CustomResourceDefinitions of a local repository between --base
+and --head. Removed CRDs, versions and fields, changed types, storage versions
+and enum values, and newly required fields are reported as breaking changes.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			changes, err := crd.DiffRefs(ctx, cfg.RepoDirectory, cfg.CRDDirectory, cfg.Base, cfg.Head)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Write(os.Stdout, changes, func(w io.Writer) { PrintChanges(w, changes) }); err != nil {
+				return err
+			}
+			if cfg.FailOnBreaking && len(changes) != 0 {
+				return fmt.Errorf("found %d breaking CRD changes between %s and %s", len(changes), cfg.Base, cfg.Head)
+			}
+			return nil
+		},
+	}
+	cfg.AddFlags(cmd)
+	cmd.Flags().StringVar(&cfg.CRDDirectory, "crd-dir", crd.DefaultCRDDirectory, "Directory of the CRD YAML files in the repository")
+	cmd.Flags().BoolVar(&cfg.FailOnBreaking, "fail-on-breaking", false, "If true, exit with an error if there are breaking changes")
+	return cmd
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package crddiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/crd"
+)
+
+func TestPrintChanges(t *testing.T) {
+	var buf bytes.Buffer
+	PrintChanges(&buf, nil)
+	assert.Equal(t, "No breaking changes\n", buf.String())
+
+	buf.Reset()
+	PrintChanges(&buf, []crd.Change{
+		{CRD: "ciliumnodes.cilium.io", Version: "v2", Field: ".spec.mtu", Change: crd.ChangeTypeChanged, Old: "integer", New: "string"},
+		{CRD: "ciliumnodes.cilium.io", Version: "v2alpha1", Change: crd.ChangeVersionRemoved},
+	})
+	assert.Equal(t, "**Breaking CRD changes:**\n"+
+		"* `ciliumnodes.cilium.io` v2: field `.spec.mtu` type changed from integer to string\n"+
+		"* `ciliumnodes.cilium.io` v2alpha1 is no longer served\n", buf.String())
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package depsdiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/release/cmd/diffcmd"
+	"github.com/cilium/release/pkg/deps"
+)
+
+// changeGroups lists the titles of the groups printed by PrintChanges, in
+// order, and the changes they contain.
+var changeGroups = []struct {
+	title string
+	match func(c deps.ModuleChange) bool
+}{
+	{"Major version changes", func(c deps.ModuleChange) bool { return c.Major }},
+	{"Upgraded", func(c deps.ModuleChange) bool { return !c.Major && c.Change == deps.ChangeUpgraded }},
+	{"Downgraded", func(c deps.ModuleChange) bool { return !c.Major && c.Change == deps.ChangeDowngraded }},
+	{"Replaced", func(c deps.ModuleChange) bool { return c.Change == deps.ChangeReplaced }},
+	{"Added", func(c deps.ModuleChange) bool { return c.Change == deps.ChangeAdded }},
+	{"Removed", func(c deps.ModuleChange) bool { return c.Change == deps.ChangeRemoved }},
+}
+
+// PrintChanges writes the changes into w as Markdown, grouped by kind of
+// change. The major version changes are listed first so that they stand out.
+func PrintChanges(w io.Writer, changes []deps.ModuleChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	first := true
+	for _, group := range changeGroups {
+		var bullets []string
+		for _, c := range changes {
+			if group.match(c) {
+				bullets = append(bullets, c.Markdown())
+			}
+		}
+		if len(bullets) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+		fmt.Fprintf(w, "**%s:**\n", group.title)
+		for _, bullet := range bullets {
+			fmt.Fprintln(w, bullet)
+		}
+	}
+}
+
+func Command(ctx context.Context, logger *log.Logger) *cobra.Command {
+	var cfg diffcmd.Config
+
+	cmd := &cobra.Command{
+		Use:   "deps-diff",
+		Short: "Show the Go modules added, removed and changed between two refs",
+		Long: `Compares the go.mod and, if present, vendor/modules.txt files of a local
+repository between --base and --head. Modules whose major version changed are
+listed first.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			changes, err := deps.DiffRefs(ctx, cfg.RepoDirectory, cfg.Base, cfg.Head)
+			if err != nil {
+				return err
+			}
+			return cfg.Write(os.Stdout, changes, func(w io.Writer) { PrintChanges(w, changes) })
+		},
+	}
+	cfg.AddFlags(cmd)
+	return cmd
+}
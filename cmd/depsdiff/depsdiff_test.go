@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package depsdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/deps"
+)
+
+func TestPrintChanges(t *testing.T) {
+	var buf bytes.Buffer
+	PrintChanges(&buf, nil)
+	assert.Equal(t, "No changes\n", buf.String())
+
+	buf.Reset()
+	PrintChanges(&buf, []deps.ModuleChange{
+		{Path: "github.com/cilium/ebpf", Change: deps.ChangeUpgraded, OldVersion: "v0.15.0", NewVersion: "v0.16.0"},
+		{Path: "github.com/new/module", Change: deps.ChangeAdded, NewVersion: "v0.1.0"},
+		{Path: "github.com/spf13/cobra", Change: deps.ChangeDowngraded, OldVersion: "v2.0.0", NewVersion: "v1.8.0", Major: true},
+		{Path: "gopkg.in/yaml.v2", NewPath: "gopkg.in/yaml.v3", Change: deps.ChangeUpgraded, OldVersion: "v2.4.0", NewVersion: "v3.0.1", Major: true},
+	})
+	assert.Equal(t, "**Major version changes:**\n"+
+		"* `github.com/spf13/cobra` v2.0.0 -> v1.8.0 (major version downgrade)\n"+
+		"* `gopkg.in/yaml.v2` v2.4.0 -> `gopkg.in/yaml.v3` v3.0.1 (major version upgrade)\n"+
+		"\n"+
+		"**Upgraded:**\n"+
+		"* `github.com/cilium/ebpf` v0.15.0 -> v0.16.0\n"+
+		"\n"+
+		"**Added:**\n"+
+		"* `github.com/new/module` v0.1.0 (added)\n", buf.String())
+}
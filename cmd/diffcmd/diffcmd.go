@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package diffcmd holds the configuration and the output handling shared by
+// the commands comparing two refs of a local repository, such as deps-diff,
+// helm-diff and crd-diff.
+package diffcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	OutputMarkdown = "markdown"
+	OutputJSON     = "json"
+)
+
+// Config configures the refs compared by a diff command and the format of its
+// output.
+type Config struct {
+	Base          string
+	Head          string
+	RepoDirectory string
+	Output        string
+}
+
+func (cfg *Config) Sanitize() error {
+	if len(cfg.Base) == 0 || len(cfg.Head) == 0 {
+		return fmt.Errorf("--base and --head can't be empty\n")
+	}
+	if len(cfg.RepoDirectory) == 0 {
+		return fmt.Errorf("--repo-dir can't be empty\n")
+	}
+	if cfg.Output != OutputMarkdown && cfg.Output != OutputJSON {
+		return fmt.Errorf("--output must be one of: %s, %s\n", OutputMarkdown, OutputJSON)
+	}
+	return nil
+}
+
+// AddFlags registers the --base, --head, --repo-dir and --output flags of cmd
+// into cfg. --base and --head are required.
+func (cfg *Config) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfg.Base, "base", "", "Base commit / tag to compare")
+	cmd.Flags().StringVar(&cfg.Head, "head", "", "Head commit / tag to compare")
+	cmd.Flags().StringVar(&cfg.RepoDirectory, "repo-dir", ".", "Local checkout of the repository")
+	cmd.Flags().StringVar(&cfg.Output, "output", OutputMarkdown, fmt.Sprintf("Output format. Accepted values: %s, %s", OutputMarkdown, OutputJSON))
+
+	for _, flag := range []string{"base", "head"} {
+		cobra.MarkFlagRequired(cmd.Flags(), flag)
+	}
+}
+
+// Write writes changes into w as indented JSON if the output format is JSON,
+// or calls printMarkdown otherwise.
+func (cfg *Config) Write(w io.Writer, changes any, printMarkdown func(io.Writer)) error {
+	if cfg.Output == OutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+	printMarkdown(w)
+	return nil
+}
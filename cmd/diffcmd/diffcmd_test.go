@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package diffcmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	cfg := Config{Base: "v1.0.0", Head: "v1.1.0", RepoDirectory: ".", Output: OutputJSON}
+	assert.NoError(t, cfg.Sanitize())
+
+	cfg.Output = "yaml"
+	assert.Error(t, cfg.Sanitize())
+
+	cfg.Output, cfg.Head = OutputMarkdown, ""
+	assert.Error(t, cfg.Sanitize())
+}
+
+func TestWrite(t *testing.T) {
+	printMarkdown := func(w io.Writer) { io.WriteString(w, "No changes\n") }
+
+	var buf bytes.Buffer
+	cfg := Config{Output: OutputMarkdown}
+	assert.NoError(t, cfg.Write(&buf, []string{"a"}, printMarkdown))
+	assert.Equal(t, "No changes\n", buf.String())
+
+	buf.Reset()
+	cfg.Output = OutputJSON
+	assert.NoError(t, cfg.Write(&buf, []string{"a"}, printMarkdown))
+	assert.Equal(t, "[\n  \"a\"\n]\n", buf.String())
+}
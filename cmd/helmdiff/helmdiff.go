@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package helmdiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/release/cmd/diffcmd"
+	"github.com/cilium/release/pkg/helm"
+)
+
+// Config configures the helm-diff command.
+type Config struct {
+	diffcmd.Config
+
+	ValuesFile   string
+	IgnoreValues []string
+}
+
+func (cfg *Config) Sanitize() error {
+	if err := cfg.Config.Sanitize(); err != nil {
+		return err
+	}
+	if len(cfg.ValuesFile) == 0 {
+		return fmt.Errorf("--values-file can't be empty\n")
+	}
+	return nil
+}
+
+// PrintChanges writes the changes into w as a Markdown "Helm Changes"
+// section.
+func PrintChanges(w io.Writer, changes []helm.ValueChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	helm.PrintValueChanges(w, changes)
+}
+
+func Command(ctx context.Context, logger *log.Logger) *cobra.Command {
+	var cfg Config
+
+	cmd := &cobra.Command{
+		Use:   "helm-diff",
+		Short: "Show the Helm values added, removed, renamed and changed between two refs",
+		Long: `Compares the default values of the Helm chart of a local repository between
+--base and --head. A removed value is reported as renamed if a value with the
+same name and default was added elsewhere, along with one of its siblings or
+under a new parent, and no other value matches as well.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := cfg.Sanitize(); err != nil {
+				cmd.Usage()
+				return fmt.Errorf("Failed to validate configuration: %s", err)
+			}
+
+			changes, err := helm.DiffValuesRefs(ctx, cfg.RepoDirectory, cfg.ValuesFile, cfg.Base, cfg.Head, cfg.IgnoreValues)
+			if err != nil {
+				return err
+			}
+			return cfg.Write(os.Stdout, changes, func(w io.Writer) { PrintChanges(w, changes) })
+		},
+	}
+	cfg.AddFlags(cmd)
+	cmd.Flags().StringVar(&cfg.ValuesFile, "values-file", helm.DefaultValuesFile, "Path of the Helm values file in the repository")
+	cmd.Flags().StringSliceVar(&cfg.IgnoreValues, "ignore-values", helm.DefaultIgnoredValues, "Patterns of the values left out of the diff, e.g. 'image.tag'")
+	return cmd
+}
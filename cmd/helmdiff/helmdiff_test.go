@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package helmdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/helm"
+)
+
+func TestPrintChanges(t *testing.T) {
+	var buf bytes.Buffer
+	PrintChanges(&buf, nil)
+	assert.Equal(t, "No changes\n", buf.String())
+
+	buf.Reset()
+	PrintChanges(&buf, []helm.ValueChange{
+		{Key: "hubble.relay.enabled", Change: helm.ValueChanged, OldValue: false, NewValue: true},
+	})
+	assert.Equal(t, "**Helm Changes:**\n"+
+		"*Default changed:*\n"+
+		"* `hubble.relay.enabled` default changed from `false` to `true`\n", buf.String())
+}
@@ -20,9 +20,13 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/cilium/release/cmd/changelog"
 	"github.com/cilium/release/cmd/checklist"
+	"github.com/cilium/release/cmd/crddiff"
+	"github.com/cilium/release/cmd/depsdiff"
+	"github.com/cilium/release/cmd/helmdiff"
 	"github.com/cilium/release/cmd/projects"
 	"github.com/cilium/release/cmd/release"
 	"github.com/cilium/release/pkg/github"
@@ -36,26 +40,54 @@ var (
 	globalCtx, cancel = context.WithCancel(context.Background())
 	logger            = log.New(os.Stderr, "", 0)
 
+	cacheCfg struct {
+		Dir        string
+		Disabled   bool
+		Clear      bool
+		GraphQLTTL time.Duration
+	}
+
 	rootCmd = &cobra.Command{
 		Use:          "release",
 		Short:        "release -- Prepare a Cilium release",
 		SilenceUsage: true,
-		Run: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cacheCfg.Clear {
+				if err := github.ClearCache(cacheCfg.Dir); err != nil {
+					return err
+				}
+			}
+			if cacheCfg.Disabled {
+				github.ConfigureCache("")
+			} else {
+				github.ConfigureCache(cacheCfg.Dir)
+			}
+			github.ConfigureGraphQLCacheTTL(cacheCfg.GraphQLTTL)
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := cfg.Sanitize(); err != nil {
 				cmd.Usage()
-				logger.Fatalf("\nFailed to validate configuration: %s", err)
+				return fmt.Errorf("\nFailed to validate configuration: %s", err)
 			}
-			run(logger)
+			return run(logger)
 		},
 	}
 )
 
 func init() {
 	addFlags(rootCmd)
+	rootCmd.PersistentFlags().StringVar(&cacheCfg.Dir, "cache-dir", github.DefaultCacheDir(), "Directory where GitHub API responses are cached. REST API responses are revalidated with conditional requests")
+	rootCmd.PersistentFlags().DurationVar(&cacheCfg.GraphQLTTL, "graphql-cache-ttl", github.DefaultGraphQLCacheTTL, "How long GraphQL API responses, which can't be revalidated, are served from the cache. If 0, they are not cached")
+	rootCmd.PersistentFlags().BoolVar(&cacheCfg.Disabled, "no-cache", false, "If true, do not use the cached GitHub API responses")
+	rootCmd.PersistentFlags().BoolVar(&cacheCfg.Clear, "clear-cache", false, "If true, remove all cached GitHub API responses before running")
 	rootCmd.AddCommand(
 		changelog.Command(globalCtx, logger),
 		projects.Command(globalCtx, logger),
 		checklist.Command(globalCtx, logger),
+		crddiff.Command(globalCtx, logger),
+		depsdiff.Command(globalCtx, logger),
+		helmdiff.Command(globalCtx, logger),
 		release.Command(globalCtx, logger),
 	)
 	go signals()
@@ -96,6 +128,8 @@ func addFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&cfg.Head, "head", "", "Head commit used to generate release notes")
 	cmd.Flags().StringVar(&cfg.LastStable, "last-stable", "", "When last stable version is set, it will be used to detect if a bug was already backported or not to that particular branch (e.g.: '1.5', '1.6')")
 	cmd.Flags().StringVar(&cfg.StateFile, "state-file", "release-state.json", "When set, it will use the already fetched information from a previous run")
+	cmd.Flags().BoolVar(&cfg.ForceStateFile, "force-state-file", false, "Use --state-file even if it was created for a different repository, base or head")
+	cmd.Flags().BoolVar(&cfg.RESTAPI, "rest-api", false, "If true, fetch the PRs one commit at a time with the REST API instead of in batches with the GraphQL API")
 	cmd.Flags().StringVar(&cfg.RepoName, "repo", "cilium/cilium", "GitHub organization and repository names separated by a slash")
 	cmd.Flags().BoolVar(&cfg.ForceMovePending, "force-move-pending-backports", false, "Force move pending backports to the next version's project")
 	cmd.Flags().StringArrayVar(&cfg.LabelFilters, "label-filter", []string{}, "Filter pull requests by labels.")
@@ -112,27 +146,31 @@ func signals() {
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	github.PrintCacheStats(os.Stderr)
+	github.PrintRateLimits(os.Stderr)
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
-func run(logger *log.Logger) {
+// run returns the error instead of exiting so that main still prints the
+// cache and rate limit statistics.
+func run(logger *log.Logger) error {
 	ghClient := github.NewClient()
 
 	if len(cfg.CurrVer) != 0 {
 		pm := projects.NewProjectManagement(ghClient, cfg.Owner, cfg.Repo)
 		err := pm.SyncProjects(globalCtx, cfg.CurrVer, cfg.NextVer, cfg.ForceMovePending)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Unable to manage project: %s\n", err)
-			os.Exit(-1)
+			return fmt.Errorf("Unable to manage project: %s", err)
 		}
-		return
+		return nil
 	}
 
-	cl, err := changelog.GenerateReleaseNotes(globalCtx, ghClient, logger, cfg.ChangeLogConfig)
+	cl, err := changelog.GenerateReleaseNotes(globalCtx, ghClient, cfg.ChangeLogConfig.GQLClient(), logger, cfg.ChangeLogConfig)
 	if err != nil {
-		logger.Fatalf("%s\n", err)
+		return err
 	}
-	cl.PrintReleaseNotes()
+	return cl.PrintReleaseNotes()
 }
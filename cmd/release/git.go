@@ -30,7 +30,9 @@ import (
 	"syscall"
 
 	"github.com/cilium/release/cmd/changelog"
+	"github.com/cilium/release/pkg/crd"
 	"github.com/cilium/release/pkg/github"
+	"github.com/cilium/release/pkg/helm"
 	io2 "github.com/cilium/release/pkg/io"
 	progressbar "github.com/schollz/progressbar/v3"
 	"golang.org/x/mod/semver"
@@ -89,6 +91,15 @@ func (pc *PrepareCommit) Run(ctx context.Context, _, _ bool, ghClient *GHClient)
 		}
 	}
 
+	// Check the CRDs before creating the local branch, so that a failed
+	// check doesn't leave it behind.
+	if pc.cfg.IsPatchRelease() {
+		err = pc.checkCRDs(ctx, remoteBranch)
+		if err != nil {
+			return err
+		}
+	}
+
 	_, err = execCommand(pc.cfg.RepoDirectory, "git", "checkout", "-b", localBranch, remoteBranch)
 	if err != nil {
 		return err
@@ -219,6 +230,27 @@ func (pc *PrepareCommit) Run(ctx context.Context, _, _ bool, ghClient *GHClient)
 	return nil
 }
 
+// checkCRDs looks for breaking changes of the CRD schemas between the previous
+// release and head, which patch releases must not contain.
+func (pc *PrepareCommit) checkCRDs(ctx context.Context, head string) error {
+	io2.Fprintf(2, os.Stdout, "Checking CRD schemas since %s\n", pc.cfg.PreviousVer)
+	changes, err := crd.DiffRefs(ctx, pc.cfg.RepoDirectory, crd.DefaultCRDDirectory, pc.cfg.PreviousVer, head)
+	if err != nil {
+		return fmt.Errorf("unable to diff the CRD schemas: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, c := range changes {
+		io2.Fprintf(3, os.Stdout, "%s\n", c.Markdown())
+	}
+	if pc.cfg.CRDBreakingChanges == CRDBreakingChangesWarn {
+		io2.Fprintf(3, os.Stdout, "⚠️ Found %d breaking CRD changes in a patch release\n", len(changes))
+		return nil
+	}
+	return fmt.Errorf("found %d breaking CRD changes in a patch release, use --crd-breaking-changes=%s to continue anyway", len(changes), CRDBreakingChangesWarn)
+}
+
 func (pc *PrepareCommit) generateChangeLog(ctx context.Context, ghClient *GHClient) error {
 	// Retrieve the SHA for the previous release.
 	previousPatchVersion := pc.cfg.PreviousVer
@@ -244,13 +276,30 @@ func (pc *PrepareCommit) generateChangeLog(ctx context.Context, ghClient *GHClie
 	io2.Fprintf(3, os.Stdout, "✍️ Generating CHANGELOG.md from %s to %s\n", previousPatchVersion, commitSha)
 	io2.Fprintf(4, os.Stdout, "Previous and current version are from different branches, using last stable %q for release notes\n", lastStable)
 	clCfg := changelog.ChangeLogConfig{
-		CommonConfig:  pc.cfg.CommonConfig,
-		Base:          previousPatchVersion,
-		Head:          commitSha,
-		StateFile:     pc.cfg.StateFile,
-		LastStable:    lastStable,
-		LabelFilters:  pc.cfg.IncludeLabels,
-		ExcludeLabels: pc.cfg.ExcludeLabels,
+		CommonConfig:       pc.cfg.CommonConfig,
+		Base:               previousPatchVersion,
+		Head:               commitSha,
+		StateFile:          pc.cfg.StateFile,
+		LastStable:         lastStable,
+		LabelFilters:       pc.cfg.IncludeLabels,
+		ExcludeLabels:      pc.cfg.ExcludeLabels,
+		TemplateFile:       pc.cfg.ChangelogTemplate,
+		GroupByLabelPrefix: pc.cfg.ChangelogGroupBy,
+		GroupTitles:        pc.cfg.ChangelogGroups,
+		NewContributors:    pc.cfg.ChangelogNewContributors,
+		GroupDependencies:  pc.cfg.ChangelogGroupDependencies,
+		DependencyAuthors:  changelog.DefaultDependencyAuthors,
+		GoModules:          pc.cfg.ChangelogGoModules,
+		EmbargoFile:        pc.cfg.ChangelogEmbargoFile,
+		EmbargoMode:        pc.cfg.ChangelogEmbargoMode,
+		RevealEmbargoed:    pc.cfg.ChangelogRevealEmbargoed,
+		SecurityAdvisories: pc.cfg.ChangelogSecurityAdvisories,
+		TargetVer:          pc.cfg.TargetVer,
+		RepoDirectory:      pc.cfg.RepoDirectory,
+		RESTAPI:            pc.cfg.RESTAPI,
+		// The release branch may have moved since the state file was
+		// created, only reuse it in that case if the user said so.
+		ForceStateFile: pc.cfg.ForceStateFile,
 	}
 	err = clCfg.Sanitize()
 	if err != nil {
@@ -259,14 +308,27 @@ func (pc *PrepareCommit) generateChangeLog(ctx context.Context, ghClient *GHClie
 	lg := &Logger{
 		depth: 3,
 	}
-	releaseNotes, err := changelog.GenerateReleaseNotes(ctx, ghClient.ghClient, lg, clCfg)
+	releaseNotes, err := changelog.GenerateReleaseNotes(ctx, ghClient.ghClient, ghClient.changelogGQLClient(clCfg), lg, clCfg)
 	if err != nil {
 		return err
 	}
 
 	var changeLogBuf bytes.Buffer
 	changeLogBuf.WriteString(fmt.Sprintf("# Changelog\n\n## %s\n\n", pc.cfg.TargetVer))
-	releaseNotes.PrintReleaseNotesForWriter(&changeLogBuf)
+	err = releaseNotes.PrintReleaseNotesAs(&changeLogBuf, changelog.OutputMarkdown)
+	if err != nil {
+		return err
+	}
+	if pc.cfg.ChangelogHelmChanges {
+		changes, err := helm.DiffValuesRefs(ctx, pc.cfg.RepoDirectory, helm.DefaultValuesFile, previousPatchVersion, commitSha, helm.DefaultIgnoredValues)
+		if err != nil {
+			return fmt.Errorf("unable to diff the Helm values: %w", err)
+		}
+		if len(changes) != 0 {
+			changeLogBuf.WriteRune('\n')
+			helm.PrintValueChanges(&changeLogBuf, changes)
+		}
+	}
 	changeLogBuf.WriteRune('\n')
 
 	versionChangesFileName := fmt.Sprintf("%s-changes.txt", pc.cfg.TargetVer)
@@ -10,11 +10,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cilium/release/cmd/changelog"
 	"github.com/cilium/release/pkg/github"
 	gh "github.com/google/go-github/v62/github"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/mod/semver"
-	"golang.org/x/oauth2"
 )
 
 type GHClient struct {
@@ -24,20 +24,20 @@ type GHClient struct {
 
 func NewGHClient() *GHClient {
 	return &GHClient{
-		ghClient: github.NewClient(),
-		ghGQLClient: githubv4.NewClient(
-			oauth2.NewClient(
-				context.Background(),
-				oauth2.StaticTokenSource(
-					&oauth2.Token{
-						AccessToken: github.Token(),
-					},
-				),
-			),
-		),
+		ghClient:    github.NewClient(),
+		ghGQLClient: github.NewGQLClient(),
 	}
 }
 
+// changelogGQLClient returns the GraphQL client to generate the changelog
+// with, which is nil if the PRs must be fetched with the REST API.
+func (ghClient *GHClient) changelogGQLClient(cfg changelog.ChangeLogConfig) *githubv4.Client {
+	if cfg.RESTAPI {
+		return nil
+	}
+	return ghClient.ghGQLClient
+}
+
 // Returns all tags for the given owner and repo.
 func (ghClient *GHClient) getTags(ctx context.Context, owner, repo string) ([]string, error) {
 	nextPage := 0
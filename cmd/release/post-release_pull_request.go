@@ -30,10 +30,86 @@ func NewSubmitPostReleasePR(cfg *ReleaseConfig) *PustPostPullRequest {
 	}
 }
 
+// sectionHeadingRegex matches the headings of the sections of the release
+// notes, e.g. "**Bugfixes:**", which are alone on their line. Notes may have
+// lines starting with bold text.
+var sectionHeadingRegex = regexp.MustCompile(`^\*\*[^*]+:\*\*$`)
+
+// changelogUpgradeNotes returns the Upgrade Notes section of the most recent
+// release in the given CHANGELOG.md, or an empty string if it has none.
+func changelogUpgradeNotes(changelog io.Reader) (string, error) {
+	var (
+		notes         []string
+		inNotes       bool
+		seenRelease   bool
+		detectVersion = regexp.MustCompile(`^## v.*$`)
+	)
+	scanner := bufio.NewScanner(changelog)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if detectVersion.MatchString(line) {
+			if seenRelease {
+				break
+			}
+			seenRelease = true
+			continue
+		}
+		if inNotes && sectionHeadingRegex.MatchString(line) {
+			break
+		}
+		if line == "**Upgrade Notes:**" {
+			inNotes = true
+		}
+		if inNotes {
+			notes = append(notes, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.Join(notes, "\n")), nil
+}
+
+// upgradeNotes returns the Upgrade Notes section of the CHANGELOG.md of the
+// repository, if any.
+func (pc *PustPostPullRequest) upgradeNotes() (string, error) {
+	changelogContent, err := os.Open(filepath.Join(pc.cfg.RepoDirectory, "CHANGELOG.md"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error reading CHANGELOG.md file: %w", err)
+	}
+	defer changelogContent.Close()
+
+	upgradeNotes, err := changelogUpgradeNotes(changelogContent)
+	if err != nil {
+		return "", fmt.Errorf("error reading CHANGELOG.md file: %w", err)
+	}
+	return upgradeNotes, nil
+}
+
 func (pc *PustPostPullRequest) Name() string {
 	return "Creating Pull Request"
 }
 
+// prependSecurityAdvisories adds the advisories that are not mentioned in the
+// release body yet at its top. Patch releases already have them in the
+// CHANGELOG.md generated while preparing the release.
+func prependSecurityAdvisories(body string, advisories []github.Advisory) string {
+	var missing []string
+	for _, adv := range advisories {
+		if !strings.Contains(body, adv.GHSAID) {
+			io2.Fprintf(2, os.Stdout, "🔒 Adding security advisory %s to the release\n", adv.GHSAID)
+			missing = append(missing, adv.Markdown())
+		}
+	}
+	if len(missing) == 0 {
+		return body
+	}
+	return "**Security Advisories:**\n" + strings.Join(missing, "\n") + "\n\n" + body
+}
+
 func (pc *PustPostPullRequest) Run(ctx context.Context, yesToPrompt, dryRun bool, ghClient *GHClient) error {
 	io2.Fprintf(1, os.Stdout, "📜 Generating a DRAFT GitHub Release\n")
 
@@ -65,6 +141,20 @@ func (pc *PustPostPullRequest) Run(ctx context.Context, yesToPrompt, dryRun bool
 			return fmt.Errorf("unable to write instruction message to release summary file: %w", err)
 		}
 
+		// The CHANGELOG.md is not part of the release body of minor
+		// releases, but its upgrade notes need to be, before the PR body
+		// so that they are not missed.
+		upgradeNotes, err := pc.upgradeNotes()
+		if err != nil {
+			return err
+		}
+		if len(upgradeNotes) != 0 {
+			io2.Fprintf(2, os.Stdout, "⚠️ Adding the upgrade notes to the release\n")
+			if _, err := releaseSummaryFileContent.WriteString(upgradeNotes + "\n\n"); err != nil {
+				return fmt.Errorf("unable to write upgrade notes to release summary file: %w", err)
+			}
+		}
+
 		// For minor releases, use the -pr-body.txt file
 		prBodyFileName := fmt.Sprintf("%s-pr-body.txt", pc.cfg.TargetVer)
 		prBodyFile := filepath.Join(pc.cfg.RepoDirectory, prBodyFileName)
@@ -121,6 +211,17 @@ func (pc *PustPostPullRequest) Run(ctx context.Context, yesToPrompt, dryRun bool
 
 	releaseSummaryFileContentStr := string(releaseSummaryFileContentBytes)
 
+	if pc.cfg.ChangelogSecurityAdvisories {
+		// The advisories are only a convenience for the draft release,
+		// which can be edited before being published.
+		advisories, err := github.SecurityAdvisories(ctx, ghClient.ghClient, pc.cfg.Owner, pc.cfg.Repo, pc.cfg.TargetVer)
+		if err != nil {
+			io2.Fprintf(2, os.Stdout, "⚠️ Unable to list the security advisories, add them manually to the release: %s\n", err)
+		} else {
+			releaseSummaryFileContentStr = prependSecurityAdvisories(releaseSummaryFileContentStr, advisories)
+		}
+	}
+
 	ersion := strings.TrimPrefix(pc.cfg.TargetVer, "v")
 	_, _, err = ghClient.ghClient.Repositories.CreateRelease(
 		ctx,
@@ -61,17 +61,22 @@ func (pm *ProjectManagement) Run(ctx context.Context, yesToPrompt, dryRun bool,
 	}
 	// Generate the CHANGELOG from previous release to current release.
 	clCfg := changelog.ChangeLogConfig{
-		CommonConfig: pm.cfg.CommonConfig,
-		Base:         pm.cfg.PreviousVer,
-		Head:         pm.cfg.TargetVer,
-		StateFile:    pm.cfg.StateFile,
+		CommonConfig:  pm.cfg.CommonConfig,
+		Base:          pm.cfg.PreviousVer,
+		Head:          pm.cfg.TargetVer,
+		StateFile:     pm.cfg.StateFile,
+		RepoDirectory: pm.cfg.RepoDirectory,
+		RESTAPI:       pm.cfg.RESTAPI,
+		// The state file was created while preparing the release, with the
+		// parent of the tagged release commit as head.
+		StateFileAnyHead: true,
 	}
 	err := clCfg.Sanitize()
 	if err != nil {
 		return err
 	}
 
-	releaseNotes, err := changelog.GenerateReleaseNotes(ctx, ghClient.ghClient, lg, clCfg)
+	releaseNotes, err := changelog.GenerateReleaseNotes(ctx, ghClient.ghClient, ghClient.changelogGQLClient(clCfg), lg, clCfg)
 	if err != nil {
 		return err
 	}
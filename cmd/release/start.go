@@ -13,6 +13,8 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/cilium/release/cmd/changelog"
+	"github.com/cilium/release/pkg/github"
 	"github.com/cilium/release/pkg/io"
 	"github.com/cilium/release/pkg/types"
 	"github.com/docker/docker/client"
@@ -24,6 +26,11 @@ const defaultStateFileValue = "release-state-${owner}-${repo}-${target-version}.
 
 var cfg ReleaseConfig
 
+const (
+	CRDBreakingChangesFail = "fail"
+	CRDBreakingChangesWarn = "warn"
+)
+
 type ReleaseConfig struct {
 	types.CommonConfig
 
@@ -35,6 +42,7 @@ type ReleaseConfig struct {
 	RemoteBranchName     string
 	DryRun               bool
 	Force                bool
+	ForceStateFile       bool
 	RepoDirectory        string
 	ReleaseRepoDirectory string
 	HelmRepoDirectory    string
@@ -42,8 +50,40 @@ type ReleaseConfig struct {
 	Steps                []string
 	DefaultBranch        string
 
-	IncludeLabels []string
-	ExcludeLabels []string
+	IncludeLabels     []string
+	ExcludeLabels     []string
+	ChangelogTemplate string
+	ChangelogGroupBy  string
+	ChangelogGroups   map[string]string
+	// ChangelogNewContributors adds a "New Contributors" section to the
+	// generated changelog.
+	ChangelogNewContributors bool
+	// ChangelogGroupDependencies lists the PRs updating dependencies in a
+	// single table of the generated changelog.
+	ChangelogGroupDependencies bool
+	// ChangelogGoModules lists the Go modules that changed since the
+	// previous release in the generated changelog.
+	ChangelogGoModules bool
+	// ChangelogSecurityAdvisories lists the published security advisories
+	// patched in the release at the top of the generated changelog and of
+	// the draft release.
+	ChangelogSecurityAdvisories bool
+	// ChangelogHelmChanges appends the Helm values added, removed, renamed
+	// and changed since the previous release to the generated changelog.
+	ChangelogHelmChanges bool
+	// ChangelogEmbargoFile, ChangelogEmbargoMode and
+	// ChangelogRevealEmbargoed configure how the embargoed release notes
+	// show up in the generated changelog and the PR body.
+	ChangelogEmbargoFile     string
+	ChangelogEmbargoMode     string
+	ChangelogRevealEmbargoed bool
+	// RESTAPI fetches the PRs of the changelog one commit at a time with
+	// the REST API instead of in batches with the GraphQL API.
+	RESTAPI bool
+
+	// CRDBreakingChanges is what to do when a patch release contains
+	// breaking changes of the CRD schemas: fail or warn.
+	CRDBreakingChanges string
 
 	// OCI registry configuration for Helm charts
 	HelmOCIRegistries []string
@@ -57,9 +97,23 @@ func (cfg *ReleaseConfig) Sanitize() error {
 	if !semver.IsValid(cfg.TargetVer) {
 		return fmt.Errorf("invalid --target-version=%s. Expected form 'vX.Y.Z(-rc.W|-pre.N)'", cfg.TargetVer)
 	}
+	if cfg.RESTAPI && cfg.ChangelogNewContributors {
+		return fmt.Errorf("--changelog-new-contributors can't be used with --rest-api")
+	}
+	if cfg.CRDBreakingChanges != CRDBreakingChangesFail && cfg.CRDBreakingChanges != CRDBreakingChangesWarn {
+		return fmt.Errorf("--crd-breaking-changes must be one of: %s, %s", CRDBreakingChangesFail, CRDBreakingChangesWarn)
+	}
 	return nil
 }
 
+// IsPatchRelease returns true if the release is neither a pre-release nor the
+// first release of a minor version.
+func (cfg *ReleaseConfig) IsPatchRelease() bool {
+	return semver.Prerelease(cfg.TargetVer) == "" &&
+		semver.Prerelease(cfg.PreviousVer) == "" &&
+		semver.MajorMinor(cfg.TargetVer) == semver.MajorMinor(cfg.PreviousVer)
+}
+
 // HasStableBranch returns true if there is a major.minor branch for the release
 // we are doing.
 func (cfg *ReleaseConfig) HasStableBranch() bool {
@@ -316,6 +370,8 @@ To start, run
 		"syncing GH projects, pushing tags. All changes that are done locally as well as creating and pushing PRs "+
 		"are also considered reversible and therefore not affected by this flag's value.")
 	cmd.Flags().BoolVar(&cfg.Force, "force", false, "Say yes to all prompts.")
+	cmd.Flags().BoolVar(&cfg.ForceStateFile, "force-state-file", false, "If true, use --state-file even if it was created for a different repository, base or head")
+	cmd.Flags().BoolVar(&cfg.RESTAPI, "rest-api", false, "If true, fetch the PRs of the changelog one commit at a time with the REST API instead of in batches with the GraphQL API")
 	cmd.Flags().StringVar(&cfg.QuayOrg, "quay-org", "cilium", "Quay.io organization to check for image vulnerabilities")
 	cmd.Flags().StringVar(&cfg.QuayRepo, "quay-repo", "cilium-ci", "Quay.io repository to check for image vulnerabilities")
 	cmd.Flags().StringVar(&cfg.RepoDirectory, "repo-dir", "../cilium", "Directory with the source code of Cilium")
@@ -328,6 +384,18 @@ To start, run
 	)
 	cmd.Flags().StringArrayVar(&cfg.IncludeLabels, "include-labels", []string{}, "Include pull requests with these labels in generated changelogs")
 	cmd.Flags().StringArrayVar(&cfg.ExcludeLabels, "exclude-labels", []string{}, "Exclude pull requests with these labels from generated changelogs")
+	cmd.Flags().StringVar(&cfg.ChangelogGroupBy, "changelog-group-by-label-prefix", "", "Group the entries of each section of the generated changelog by their label with this prefix, e.g. 'area/'")
+	cmd.Flags().StringToStringVar(&cfg.ChangelogGroups, "changelog-group-title", map[string]string{}, "Heading of the group of a label in the generated changelog, e.g. 'area/datapath=Datapath'")
+	cmd.Flags().BoolVar(&cfg.ChangelogNewContributors, "changelog-new-contributors", false, "If true, list the authors whose first merged PR is part of the release in the generated changelog")
+	cmd.Flags().BoolVar(&cfg.ChangelogGroupDependencies, "changelog-group-dependencies", false, "If true, list the PRs updating dependencies in a single table of the generated changelog")
+	cmd.Flags().BoolVar(&cfg.ChangelogGoModules, "changelog-go-modules", false, "If true, list the Go modules added, removed and changed since the previous release in the generated changelog")
+	cmd.Flags().BoolVar(&cfg.ChangelogSecurityAdvisories, "changelog-security-advisories", false, "If true, list the published security advisories patched in the release at the top of the generated changelog and of the draft release")
+	cmd.Flags().BoolVar(&cfg.ChangelogHelmChanges, "changelog-helm-changes", false, "If true, append the Helm values added, removed, renamed and changed since the previous release to the generated changelog")
+	cmd.Flags().StringVar(&cfg.ChangelogEmbargoFile, "changelog-embargo-file", "", "YAML file listing the PRs whose release notes are embargoed, in addition to the ones labeled "+github.EmbargoedLabel)
+	cmd.Flags().StringVar(&cfg.ChangelogEmbargoMode, "changelog-embargo-mode", changelog.EmbargoPlaceholder, fmt.Sprintf("How the embargoed release notes are published in the generated changelog. Accepted values: %s", strings.Join(changelog.EmbargoModes, ", ")))
+	cmd.Flags().BoolVar(&cfg.ChangelogRevealEmbargoed, "changelog-reveal-embargoed", false, "If true, publish the embargoed release notes as is in the generated changelog, e.g. once their advisories are published")
+	cmd.Flags().StringVar(&cfg.CRDBreakingChanges, "crd-breaking-changes", CRDBreakingChangesFail, fmt.Sprintf("What to do when a patch release contains breaking changes of the CRD schemas. Accepted values: %s, %s", CRDBreakingChangesFail, CRDBreakingChangesWarn))
+	cmd.Flags().StringVar(&cfg.ChangelogTemplate, "changelog-template", "", "Go text/template file used to render the generated changelog. Defaults to the built-in template")
 
 	for _, flag := range []string{"target-version", "template"} {
 		cobra.MarkFlagRequired(cmd.Flags(), flag)
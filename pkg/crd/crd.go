@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package crd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cilium/release/pkg/git"
+)
+
+// DefaultCRDDirectory is the directory, in the Cilium repository, containing
+// the CustomResourceDefinitions.
+const DefaultCRDDirectory = "pkg/k8s/apis/cilium.io/client/crds"
+
+const (
+	ChangeCRDRemoved            = "crd-removed"
+	ChangeVersionRemoved        = "version-removed"
+	ChangeStorageVersionChanged = "storage-version-changed"
+	ChangeFieldRemoved          = "field-removed"
+	ChangeTypeChanged           = "type-changed"
+	ChangeFieldRequired         = "field-required"
+	ChangeEnumValueRemoved      = "enum-value-removed"
+)
+
+// Schema is the subset of an OpenAPI v3 schema needed to detect breaking
+// changes.
+type Schema struct {
+	Type       string             `yaml:"type"`
+	Properties map[string]*Schema `yaml:"properties"`
+	Items      *Schema            `yaml:"items"`
+	Required   []string           `yaml:"required"`
+	Enum       []any              `yaml:"enum"`
+}
+
+// Version is a version of a CustomResourceDefinition.
+type Version struct {
+	Name    string `yaml:"name"`
+	Served  bool   `yaml:"served"`
+	Storage bool   `yaml:"storage"`
+	Schema  struct {
+		OpenAPIV3Schema *Schema `yaml:"openAPIV3Schema"`
+	} `yaml:"schema"`
+}
+
+// CustomResourceDefinition is the subset of a CustomResourceDefinition needed
+// to detect breaking changes.
+type CustomResourceDefinition struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Versions []Version `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// version returns the served version with the given name, if any.
+func (crd *CustomResourceDefinition) version(name string) *Version {
+	for i, v := range crd.Spec.Versions {
+		if v.Name == name && v.Served {
+			return &crd.Spec.Versions[i]
+		}
+	}
+	return nil
+}
+
+func (crd *CustomResourceDefinition) storageVersion() string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// Change is a breaking change of a CustomResourceDefinition. Fields are
+// JSON paths relative to the root of the resource, e.g. ".spec.nodeSelector",
+// where "[]" stands for the items of an array.
+type Change struct {
+	CRD     string `json:"crd" yaml:"crd"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	Field   string `json:"field,omitempty" yaml:"field,omitempty"`
+	Change  string `json:"change" yaml:"change"`
+	Old     string `json:"old,omitempty" yaml:"old,omitempty"`
+	New     string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// Markdown returns the change as a Markdown bullet.
+func (c Change) Markdown() string {
+	prefix := fmt.Sprintf("* `%s`", c.CRD)
+	if len(c.Version) != 0 {
+		prefix += " " + c.Version
+	}
+	switch c.Change {
+	case ChangeCRDRemoved:
+		return prefix + " was removed"
+	case ChangeVersionRemoved:
+		return prefix + " is no longer served"
+	case ChangeStorageVersionChanged:
+		return fmt.Sprintf("%s storage version changed from %s to %s", prefix, c.Old, c.New)
+	case ChangeFieldRemoved:
+		return fmt.Sprintf("%s: field `%s` was removed", prefix, c.Field)
+	case ChangeTypeChanged:
+		return fmt.Sprintf("%s: field `%s` type changed from %s to %s", prefix, c.Field, c.Old, c.New)
+	case ChangeFieldRequired:
+		return fmt.Sprintf("%s: field `%s` is now required", prefix, c.Field)
+	default:
+		return fmt.Sprintf("%s: field `%s` no longer accepts %s", prefix, c.Field, c.Old)
+	}
+}
+
+// Parse returns the CustomResourceDefinitions found in the YAML documents of
+// data, indexed by name. Other kinds of documents are ignored.
+func Parse(data string) (map[string]*CustomResourceDefinition, error) {
+	crds := map[string]*CustomResourceDefinition{}
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	for {
+		var crd CustomResourceDefinition
+		err := dec.Decode(&crd)
+		if errors.Is(err, io.EOF) {
+			return crds, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if crd.Kind == "CustomResourceDefinition" && len(crd.Metadata.Name) != 0 {
+			crds[crd.Metadata.Name] = &crd
+		}
+	}
+}
+
+// Diff returns the breaking changes between the old and new
+// CustomResourceDefinitions, sorted by CRD then field. Removing a CRD
+// or a served version, changing the storage version, removing a field,
+// changing its type or enum values, and requiring a field that was optional
+// are all breaking changes.
+func Diff(old, new map[string]*CustomResourceDefinition) []Change {
+	var changes []Change
+	for _, name := range slices.Sorted(maps.Keys(old)) {
+		oldCRD, newCRD := old[name], new[name]
+		if newCRD == nil {
+			changes = append(changes, Change{CRD: name, Change: ChangeCRDRemoved})
+			continue
+		}
+		oldStorage, newStorage := oldCRD.storageVersion(), newCRD.storageVersion()
+		if oldStorage != newStorage {
+			changes = append(changes, Change{CRD: name, Change: ChangeStorageVersionChanged, Old: oldStorage, New: newStorage})
+		}
+		for _, oldVersion := range oldCRD.Spec.Versions {
+			if !oldVersion.Served {
+				continue
+			}
+			newVersion := newCRD.version(oldVersion.Name)
+			if newVersion == nil {
+				changes = append(changes, Change{CRD: name, Version: oldVersion.Name, Change: ChangeVersionRemoved})
+				continue
+			}
+			for _, c := range diffSchema("", oldVersion.Schema.OpenAPIV3Schema, newVersion.Schema.OpenAPIV3Schema) {
+				c.CRD, c.Version = name, oldVersion.Name
+				changes = append(changes, c)
+			}
+		}
+	}
+	return changes
+}
+
+func diffSchema(field string, old, new *Schema) []Change {
+	if old == nil || new == nil {
+		return nil
+	}
+	if len(old.Type) != 0 && len(new.Type) != 0 && old.Type != new.Type {
+		return []Change{{Field: field, Change: ChangeTypeChanged, Old: old.Type, New: new.Type}}
+	}
+
+	var changes []Change
+	if len(new.Enum) != 0 {
+		for _, value := range old.Enum {
+			if !slices.Contains(new.Enum, value) {
+				changes = append(changes, Change{Field: field, Change: ChangeEnumValueRemoved, Old: fmt.Sprint(value)})
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(old.Properties)) {
+		child := field + "." + name
+		if _, ok := new.Properties[name]; !ok {
+			changes = append(changes, Change{Field: child, Change: ChangeFieldRemoved})
+			continue
+		}
+		if slices.Contains(new.Required, name) && !slices.Contains(old.Required, name) {
+			changes = append(changes, Change{Field: child, Change: ChangeFieldRequired})
+		}
+		changes = append(changes, diffSchema(child, old.Properties[name], new.Properties[name])...)
+	}
+	// A new field is only breaking if it's required, since existing
+	// resources don't have it.
+	for _, name := range slices.Sorted(maps.Keys(new.Properties)) {
+		if _, ok := old.Properties[name]; !ok && slices.Contains(new.Required, name) {
+			changes = append(changes, Change{Field: field + "." + name, Change: ChangeFieldRequired})
+		}
+	}
+	changes = append(changes, diffSchema(field+"[]", old.Items, new.Items)...)
+
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return changes
+}
+
+// Load returns the CustomResourceDefinitions of the YAML files under crdDir
+// in ref of the repository located in dir.
+func Load(ctx context.Context, dir, crdDir, ref string) (map[string]*CustomResourceDefinition, error) {
+	files, err := git.ListFiles(ctx, dir, ref, crdDir)
+	if err != nil {
+		return nil, err
+	}
+	crds := map[string]*CustomResourceDefinition{}
+	for _, file := range files {
+		if ext := path.Ext(file); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		data, _, err := git.ShowFile(ctx, dir, ref, file)
+		if err != nil {
+			return nil, err
+		}
+		fileCRDs, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s at %s: %w", file, ref, err)
+		}
+		maps.Copy(crds, fileCRDs)
+	}
+	return crds, nil
+}
+
+// DiffRefs returns the breaking changes of the CustomResourceDefinitions
+// under crdDir in the repository located in dir between base and head.
+func DiffRefs(ctx context.Context, dir, crdDir, base, head string) ([]Change, error) {
+	old, err := Load(ctx, dir, crdDir, base)
+	if err != nil {
+		return nil, err
+	}
+	new, err := Load(ctx, dir, crdDir, head)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(old, new), nil
+}
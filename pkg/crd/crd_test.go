@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package crd
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/git/gittest"
+)
+
+const oldCRDs = `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: ciliumnodes.cilium.io
+spec:
+  versions:
+  - name: v2alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              mode:
+                type: string
+                enum: [native, tunnel]
+              addresses:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    ip:
+                      type: string
+                    type:
+                      type: string
+              mtu:
+                type: integer
+              legacy:
+                type: boolean
+  - name: v2
+    served: true
+    storage: false
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-a-crd
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: ciliumfoos.cilium.io
+spec:
+  versions:
+  - name: v2
+    served: true
+    storage: true
+`
+
+const newCRDs = `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: ciliumnodes.cilium.io
+spec:
+  versions:
+  - name: v2alpha1
+    served: false
+    storage: false
+  - name: v2
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              mode:
+                type: string
+                enum: [native]
+              addresses:
+                type: array
+                items:
+                  type: object
+                  required: [ip]
+                  properties:
+                    ip:
+                      type: string
+                    type:
+                      type: string
+              mtu:
+                type: string
+              new:
+                type: boolean
+`
+
+func TestDiff(t *testing.T) {
+	old, err := Parse(oldCRDs)
+	assert.NoError(t, err)
+	assert.Len(t, old, 2)
+	new, err := Parse(newCRDs)
+	assert.NoError(t, err)
+
+	// Compare v2alpha1 of the old CRD with v2 of the new one.
+	new["ciliumnodes.cilium.io"].Spec.Versions[1].Name = "v2alpha1"
+	new["ciliumnodes.cilium.io"].Spec.Versions = append(new["ciliumnodes.cilium.io"].Spec.Versions, Version{Name: "v2", Served: true})
+
+	changes := Diff(old, new)
+	assert.Equal(t, []Change{
+		{CRD: "ciliumfoos.cilium.io", Change: ChangeCRDRemoved},
+		{CRD: "ciliumnodes.cilium.io", Version: "v2alpha1", Field: ".spec.addresses[].ip", Change: ChangeFieldRequired},
+		{CRD: "ciliumnodes.cilium.io", Version: "v2alpha1", Field: ".spec.legacy", Change: ChangeFieldRemoved},
+		{CRD: "ciliumnodes.cilium.io", Version: "v2alpha1", Field: ".spec.mode", Change: ChangeEnumValueRemoved, Old: "tunnel"},
+		{CRD: "ciliumnodes.cilium.io", Version: "v2alpha1", Field: ".spec.mtu", Change: ChangeTypeChanged, Old: "integer", New: "string"},
+	}, changes)
+}
+
+func TestDiffVersions(t *testing.T) {
+	old, err := Parse(oldCRDs)
+	assert.NoError(t, err)
+	new, err := Parse(newCRDs)
+	assert.NoError(t, err)
+
+	changes := Diff(old, new)
+	assert.Equal(t, []Change{
+		{CRD: "ciliumfoos.cilium.io", Change: ChangeCRDRemoved},
+		{CRD: "ciliumnodes.cilium.io", Change: ChangeStorageVersionChanged, Old: "v2alpha1", New: "v2"},
+		{CRD: "ciliumnodes.cilium.io", Version: "v2alpha1", Change: ChangeVersionRemoved},
+	}, changes)
+
+	var bullets []string
+	for _, c := range changes {
+		bullets = append(bullets, c.Markdown())
+	}
+	assert.Equal(t, []string{
+		"* `ciliumfoos.cilium.io` was removed",
+		"* `ciliumnodes.cilium.io` storage version changed from v2alpha1 to v2",
+		"* `ciliumnodes.cilium.io` v2alpha1 is no longer served",
+	}, bullets)
+	assert.Equal(t, "* `ciliumnodes.cilium.io` v2: field `.spec.mode` no longer accepts tunnel",
+		Change{CRD: "ciliumnodes.cilium.io", Version: "v2", Field: ".spec.mode", Change: ChangeEnumValueRemoved, Old: "tunnel"}.Markdown())
+}
+
+func TestDiffRefs(t *testing.T) {
+	ctx := context.Background()
+	dir := gittest.NewRepo(t)
+	crdFile := path.Join(DefaultCRDDirectory, "v2", "crds.yaml")
+	gittest.Commit(t, dir, "crds", map[string]string{crdFile: oldCRDs})
+	gittest.Run(t, dir, "tag", "v1.0.0")
+	gittest.Commit(t, dir, "crds", map[string]string{crdFile: newCRDs})
+
+	changes, err := DiffRefs(ctx, dir, DefaultCRDDirectory, "v1.0.0", "main")
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	changes, err = DiffRefs(ctx, dir, DefaultCRDDirectory, "v1.0.0", "v1.0.0")
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
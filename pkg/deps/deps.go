@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package deps compares the Go modules required by a repository at two refs.
+package deps
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/cilium/release/pkg/git"
+)
+
+const (
+	ChangeAdded      = "added"
+	ChangeRemoved    = "removed"
+	ChangeUpgraded   = "upgraded"
+	ChangeDowngraded = "downgraded"
+	// ChangeReplaced is a module whose version can't be compared, e.g.
+	// because it is replaced by a local directory.
+	ChangeReplaced = "replaced"
+)
+
+// ModuleChange is a Go module that was added, removed or whose version
+// changed between two refs.
+type ModuleChange struct {
+	Path string `json:"path" yaml:"path"`
+	// NewPath is set if the module path changed along with its major
+	// version, e.g. from github.com/foo/bar to github.com/foo/bar/v2.
+	NewPath    string `json:"newPath,omitempty" yaml:"newPath,omitempty"`
+	Change     string `json:"change" yaml:"change"`
+	OldVersion string `json:"oldVersion,omitempty" yaml:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty" yaml:"newVersion,omitempty"`
+	// Major is true if the major version of the module changed.
+	Major bool `json:"major,omitempty" yaml:"major,omitempty"`
+}
+
+// Markdown returns the change as a Markdown bullet.
+func (c ModuleChange) Markdown() string {
+	// Replacements are shown without their "=> " prefix.
+	oldVersion := strings.TrimPrefix(c.OldVersion, "=> ")
+	newVersion := strings.TrimPrefix(c.NewVersion, "=> ")
+	switch c.Change {
+	case ChangeAdded:
+		return fmt.Sprintf("* `%s` %s (added)", c.Path, newVersion)
+	case ChangeRemoved:
+		return fmt.Sprintf("* `%s` %s (removed)", c.Path, oldVersion)
+	}
+	text := fmt.Sprintf("* `%s` %s -> %s", c.Path, oldVersion, newVersion)
+	if len(c.NewPath) != 0 {
+		text = fmt.Sprintf("* `%s` %s -> `%s` %s", c.Path, oldVersion, c.NewPath, newVersion)
+	}
+	switch {
+	case c.Change == ChangeReplaced:
+		text += " (replaced)"
+	case c.Major && c.Change == ChangeDowngraded:
+		text += " (major version downgrade)"
+	case c.Major:
+		text += " (major version upgrade)"
+	}
+	return text
+}
+
+// ParseGoMod returns the version of each module required by the go.mod file
+// data, with its replace directives applied. Modules replaced by another
+// module, or by a local directory, have a version of the form
+// "=> path [version]".
+func ParseGoMod(data string) (map[string]string, error) {
+	f, err := modfile.ParseLax("go.mod", []byte(data), nil)
+	if err != nil {
+		return nil, err
+	}
+	modules := map[string]string{}
+	for _, r := range f.Require {
+		modules[r.Mod.Path] = r.Mod.Version
+	}
+
+	// ParseLax ignores the replace directives, which only apply to the main
+	// module, while Parse fails on the directives added by newer Go versions.
+	// Parse the replace directives on their own.
+	var replaces []modfile.Expr
+	for _, stmt := range f.Syntax.Stmt {
+		switch stmt := stmt.(type) {
+		case *modfile.Line:
+			if stmt.Token[0] == "replace" {
+				replaces = append(replaces, stmt)
+			}
+		case *modfile.LineBlock:
+			if stmt.Token[0] == "replace" {
+				replaces = append(replaces, stmt)
+			}
+		}
+	}
+	r, err := modfile.Parse("go.mod", modfile.Format(&modfile.FileSyntax{Stmt: replaces}), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range r.Replace {
+		if _, ok := modules[r.Old.Path]; ok {
+			modules[r.Old.Path] = replacement(r.Old, r.New)
+		}
+	}
+	return modules, nil
+}
+
+// replacement returns the version of the module old once replaced by new:
+// the version of new if it only replaces the version of old, or
+// "=> path [version]" otherwise.
+func replacement(old, new module.Version) string {
+	if new.Path == old.Path && len(new.Version) != 0 {
+		return new.Version
+	}
+	return strings.TrimSpace("=> " + new.Path + " " + new.Version)
+}
+
+// ParseModulesTxt returns the version of each module listed in the
+// vendor/modules.txt file data. Replaced modules have the same version as in
+// ParseGoMod.
+func ParseModulesTxt(data string) map[string]string {
+	modules := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		// Module lines start with "# ", annotations with "## ".
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		// Module lines are either "path version" or
+		// "path [version] => path [version]".
+		oldFields, newFields, replaced := strings.Cut(strings.TrimPrefix(line, "# "), "=>")
+		old, new := moduleVersion(oldFields), moduleVersion(newFields)
+		switch {
+		case len(old.Path) == 0:
+		case replaced && len(new.Path) != 0:
+			modules[old.Path] = replacement(old, new)
+		case len(old.Version) != 0:
+			modules[old.Path] = old.Version
+		}
+	}
+	return modules
+}
+
+// moduleVersion parses the "path [version]" fields of a vendor/modules.txt
+// line.
+func moduleVersion(fields string) module.Version {
+	var m module.Version
+	f := strings.Fields(fields)
+	if len(f) > 0 {
+		m.Path = f[0]
+	}
+	if len(f) > 1 {
+		m.Version = f[1]
+	}
+	return m
+}
+
+// majorSuffixRegex matches the major version suffix of module paths, e.g.
+// "/v2" or, for gopkg.in, ".v3".
+var majorSuffixRegex = regexp.MustCompile(`[/.]v[0-9]+$`)
+
+// Diff returns the modules added, removed and changed between old and new,
+// sorted by path. A module whose path changed with its major version, e.g.
+// from foo/v2 to foo/v3, is reported as a single change.
+func Diff(old, new map[string]string) []ModuleChange {
+	var changes []ModuleChange
+	added := map[string]string{}
+	removed := map[string]string{}
+	for path, newVersion := range new {
+		oldVersion, ok := old[path]
+		switch {
+		case !ok:
+			added[path] = newVersion
+		case oldVersion != newVersion:
+			changes = append(changes, versionChange(path, oldVersion, newVersion))
+		}
+	}
+	for path := range old {
+		if _, ok := new[path]; !ok {
+			removed[path] = old[path]
+		}
+	}
+
+	// Pair the modules whose path only differs by its major version suffix.
+	for _, oldPath := range slices.Sorted(maps.Keys(removed)) {
+		prefix := majorSuffixRegex.ReplaceAllString(oldPath, "")
+		for _, newPath := range slices.Sorted(maps.Keys(added)) {
+			if majorSuffixRegex.ReplaceAllString(newPath, "") != prefix {
+				continue
+			}
+			c := versionChange(oldPath, removed[oldPath], added[newPath])
+			c.NewPath, c.Major = newPath, true
+			changes = append(changes, c)
+			delete(removed, oldPath)
+			delete(added, newPath)
+			break
+		}
+	}
+	for path, version := range added {
+		changes = append(changes, ModuleChange{Path: path, Change: ChangeAdded, NewVersion: version})
+	}
+	for path, version := range removed {
+		changes = append(changes, ModuleChange{Path: path, Change: ChangeRemoved, OldVersion: version})
+	}
+
+	slices.SortFunc(changes, func(a, b ModuleChange) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+func versionChange(path, oldVersion, newVersion string) ModuleChange {
+	c := ModuleChange{Path: path, OldVersion: oldVersion, NewVersion: newVersion}
+	if !semver.IsValid(oldVersion) || !semver.IsValid(newVersion) {
+		c.Change = ChangeReplaced
+		return c
+	}
+	c.Change = ChangeUpgraded
+	if semver.Compare(oldVersion, newVersion) > 0 {
+		c.Change = ChangeDowngraded
+	}
+	c.Major = semver.Major(oldVersion) != semver.Major(newVersion)
+	return c
+}
+
+// Modules returns the modules required by the main module of the repository
+// located in dir at ref. The versions of vendor/modules.txt, if present,
+// take precedence over the ones of go.mod.
+func Modules(ctx context.Context, dir, ref string) (map[string]string, error) {
+	goMod, _, err := git.ShowFile(ctx, dir, ref, "go.mod")
+	if err != nil {
+		return nil, err
+	}
+	modules, err := ParseGoMod(goMod)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse go.mod: %w", err)
+	}
+	modulesTxt, ok, err := git.ShowFile(ctx, dir, ref, "vendor/modules.txt")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		maps.Copy(modules, ParseModulesTxt(modulesTxt))
+	}
+	return modules, nil
+}
+
+// DiffRefs returns the changes of the modules required by the repository
+// located in dir between base and head.
+func DiffRefs(ctx context.Context, dir, base, head string) ([]ModuleChange, error) {
+	old, err := Modules(ctx, dir, base)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the Go modules of %s: %w", base, err)
+	}
+	new, err := Modules(ctx, dir, head)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the Go modules of %s: %w", head, err)
+	}
+	return Diff(old, new), nil
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package deps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/git/gittest"
+)
+
+func TestParseGoMod(t *testing.T) {
+	goMod := `module github.com/cilium/cilium
+
+go 1.22.0
+
+require github.com/cilium/ebpf v0.15.0
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	"go.uber.org/zap" v1.27.0
+	k8s.io/api v0.30.3
+)
+
+replace github.com/cilium/ebpf => github.com/cilium/ebpf v0.15.1-0.20240701
+
+replace (
+	k8s.io/api => ../api
+	github.com/not/required => github.com/fork/required v1.0.0
+)
+
+tool golang.org/x/tools/cmd/stringer
+`
+	modules, err := ParseGoMod(goMod)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"github.com/cilium/ebpf":   "v0.15.1-0.20240701",
+		"github.com/google/go-cmp": "v0.6.0",
+		"go.uber.org/zap":          "v1.27.0",
+		"k8s.io/api":               "=> ../api",
+	}, modules)
+
+	_, err = ParseGoMod("require (\n")
+	assert.Error(t, err)
+}
+
+func TestParseModulesTxt(t *testing.T) {
+	modulesTxt := `# github.com/cilium/ebpf v0.15.0 => github.com/cilium/ebpf v0.15.1
+## explicit; go 1.21
+github.com/cilium/ebpf
+# github.com/google/go-cmp v0.6.0
+## explicit; go 1.13
+github.com/google/go-cmp/cmp
+# k8s.io/api => ../api
+`
+	assert.Equal(t, map[string]string{
+		"github.com/cilium/ebpf":   "v0.15.1",
+		"github.com/google/go-cmp": "v0.6.0",
+		"k8s.io/api":               "=> ../api",
+	}, ParseModulesTxt(modulesTxt))
+}
+
+func TestDiff(t *testing.T) {
+	old := map[string]string{
+		"github.com/cilium/ebpf":     "v0.15.0",
+		"github.com/google/go-cmp":   "v0.6.0",
+		"github.com/old/module":      "v1.0.0",
+		"github.com/spf13/cobra":     "v1.8.1",
+		"github.com/vishvananda/foo": "v0.0.0-20240101000000-abcdefabcdef",
+		"gopkg.in/yaml.v2":           "v2.4.0",
+		"k8s.io/api":                 "v0.30.3",
+	}
+	new := map[string]string{
+		"github.com/cilium/ebpf":     "v0.16.0",
+		"github.com/google/go-cmp":   "v0.6.0",
+		"github.com/new/module":      "v0.1.0",
+		"github.com/spf13/cobra":     "v1.8.0",
+		"github.com/vishvananda/foo": "v1.0.0",
+		"gopkg.in/yaml.v3":           "v3.0.1",
+		"k8s.io/api":                 "=> ../api",
+	}
+	changes := Diff(old, new)
+	assert.Equal(t, []ModuleChange{
+		{Path: "github.com/cilium/ebpf", Change: ChangeUpgraded, OldVersion: "v0.15.0", NewVersion: "v0.16.0"},
+		{Path: "github.com/new/module", Change: ChangeAdded, NewVersion: "v0.1.0"},
+		{Path: "github.com/old/module", Change: ChangeRemoved, OldVersion: "v1.0.0"},
+		{Path: "github.com/spf13/cobra", Change: ChangeDowngraded, OldVersion: "v1.8.1", NewVersion: "v1.8.0"},
+		{Path: "github.com/vishvananda/foo", Change: ChangeUpgraded, OldVersion: "v0.0.0-20240101000000-abcdefabcdef", NewVersion: "v1.0.0", Major: true},
+		{Path: "gopkg.in/yaml.v2", NewPath: "gopkg.in/yaml.v3", Change: ChangeUpgraded, OldVersion: "v2.4.0", NewVersion: "v3.0.1", Major: true},
+		{Path: "k8s.io/api", Change: ChangeReplaced, OldVersion: "v0.30.3", NewVersion: "=> ../api"},
+	}, changes)
+
+	var bullets []string
+	for _, c := range changes {
+		bullets = append(bullets, c.Markdown())
+	}
+	assert.Equal(t, []string{
+		"* `github.com/cilium/ebpf` v0.15.0 -> v0.16.0",
+		"* `github.com/new/module` v0.1.0 (added)",
+		"* `github.com/old/module` v1.0.0 (removed)",
+		"* `github.com/spf13/cobra` v1.8.1 -> v1.8.0",
+		"* `github.com/vishvananda/foo` v0.0.0-20240101000000-abcdefabcdef -> v1.0.0 (major version upgrade)",
+		"* `gopkg.in/yaml.v2` v2.4.0 -> `gopkg.in/yaml.v3` v3.0.1 (major version upgrade)",
+		"* `k8s.io/api` v0.30.3 -> ../api (replaced)",
+	}, bullets)
+}
+
+func TestDiffRefs(t *testing.T) {
+	ctx := context.Background()
+	dir := gittest.NewRepo(t)
+	gittest.Commit(t, dir, "go.mod", map[string]string{
+		"go.mod": "module foo\n\nrequire github.com/cilium/ebpf v0.15.0\n",
+	})
+	gittest.Run(t, dir, "tag", "v1.0.0")
+	gittest.Commit(t, dir, "vendor", map[string]string{
+		"vendor/modules.txt": "# github.com/cilium/ebpf v0.15.0 => github.com/cilium/ebpf v0.16.0\n",
+	})
+
+	changes, err := DiffRefs(ctx, dir, "v1.0.0", "main")
+	assert.NoError(t, err)
+	assert.Equal(t, []ModuleChange{
+		{Path: "github.com/cilium/ebpf", Change: ChangeUpgraded, OldVersion: "v0.15.0", NewVersion: "v0.16.0"},
+	}, changes)
+
+	_, err = DiffRefs(ctx, dir, "does-not-exist", "main")
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Run executes git with the given arguments in the repository located in dir
+// and returns its standard output.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	cmd.Stdout = &stdout
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("unable to run command %q: %w\n%s",
+			"git "+strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// RevParse resolves ref into a commit SHA.
+func RevParse(ctx context.Context, dir, ref string) (string, error) {
+	out, err := Run(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %q: %w", ref, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// MergeBase returns the best common ancestor of base and head.
+func MergeBase(ctx context.Context, dir, base, head string) (string, error) {
+	out, err := Run(ctx, dir, "merge-base", base, head)
+	if err != nil {
+		return "", fmt.Errorf("unable to find merge base of %s and %s: %w", base, head, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Commits returns the SHAs of all commits reachable from head but not from
+// base, ordered from head to base.
+func Commits(ctx context.Context, dir, base, head string) ([]string, error) {
+	out, err := Run(ctx, dir, "rev-list", base+".."+head)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// CommitMessages returns the messages of all commits reachable from head but
+// not from base, indexed by SHA.
+func CommitMessages(ctx context.Context, dir, base, head string) (map[string]string, error) {
+	out, err := Run(ctx, dir, "log", "-z", "--format=%H%n%B", base+".."+head)
+	if err != nil {
+		return nil, err
+	}
+	messages := map[string]string{}
+	for _, commit := range strings.Split(out, "\x00") {
+		if sha, message, ok := strings.Cut(commit, "\n"); ok {
+			messages[sha] = strings.TrimRight(message, "\n")
+		}
+	}
+	return messages, nil
+}
+
+// ShowFile returns the content of the file at path in ref. ok is false if
+// ref doesn't contain such a file.
+func ShowFile(ctx context.Context, dir, ref, path string) (content string, ok bool, err error) {
+	sha, err := RevParse(ctx, dir, ref)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := Run(ctx, dir, "cat-file", "-e", sha+":"+path); err != nil {
+		return "", false, nil
+	}
+	out, err := Run(ctx, dir, "show", sha+":"+path)
+	if err != nil {
+		return "", false, err
+	}
+	return out, true, nil
+}
+
+// ListFiles returns the paths of the files under dirPath in ref, sorted.
+func ListFiles(ctx context.Context, dir, ref, dirPath string) ([]string, error) {
+	sha, err := RevParse(ctx, dir, ref)
+	if err != nil {
+		return nil, err
+	}
+	out, err := Run(ctx, dir, "ls-tree", "-r", "--name-only", sha, "--", dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
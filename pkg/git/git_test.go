@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/git/gittest"
+)
+
+// newTestRepo creates a git repository with the following history and returns
+// its directory and the SHAs of commits A, B, C and D:
+//
+//	A - B - C   (main)
+//	     \
+//	      D     (feature)
+func newTestRepo(t *testing.T) (string, []string) {
+	dir := gittest.NewRepo(t)
+	a := gittest.Commit(t, dir, "A", nil)
+	b := gittest.Commit(t, dir, "B", nil)
+	c := gittest.Commit(t, dir, "C", nil)
+	gittest.Run(t, dir, "checkout", "-q", "-b", "feature", b)
+	d := gittest.Commit(t, dir, "D", nil)
+	return dir, []string{a, b, c, d}
+}
+
+func TestCommits(t *testing.T) {
+	dir, shas := newTestRepo(t)
+	ctx := context.Background()
+
+	commits, err := Commits(ctx, dir, shas[0], "main")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{shas[2], shas[1]}, commits)
+
+	mergeBase, err := MergeBase(ctx, dir, "main", "feature")
+	assert.NoError(t, err)
+	assert.Equal(t, shas[1], mergeBase)
+
+	commits, err = Commits(ctx, dir, mergeBase, "feature")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{shas[3]}, commits)
+
+	messages, err := CommitMessages(ctx, dir, shas[0], "main")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{shas[1]: "B", shas[2]: "C"}, messages)
+
+	_, err = RevParse(ctx, dir, "does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestShowFile(t *testing.T) {
+	dir, shas := newTestRepo(t)
+	ctx := context.Background()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module foo\n"), 0o644))
+	_, err := Run(ctx, dir, "add", "go.mod")
+	assert.NoError(t, err)
+	_, err = Run(ctx, dir, "commit", "-q", "-m", "E")
+	assert.NoError(t, err)
+
+	content, ok, err := ShowFile(ctx, dir, "feature", "go.mod")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "module foo\n", content)
+
+	_, ok, err = ShowFile(ctx, dir, shas[0], "go.mod")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = ShowFile(ctx, dir, "does-not-exist", "go.mod")
+	assert.Error(t, err)
+}
+
+func TestListFiles(t *testing.T) {
+	dir, shas := newTestRepo(t)
+	ctx := context.Background()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "crds", "v2"), 0o755))
+	for _, name := range []string{"crds/v2/b.yaml", "crds/a.yaml"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("kind: Foo\n"), 0o644))
+		_, err := Run(ctx, dir, "add", name)
+		assert.NoError(t, err)
+	}
+	_, err := Run(ctx, dir, "commit", "-q", "-m", "E")
+	assert.NoError(t, err)
+
+	files, err := ListFiles(ctx, dir, "feature", "crds")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"crds/a.yaml", "crds/v2/b.yaml"}, files)
+
+	files, err = ListFiles(ctx, dir, shas[0], "crds")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gh "github.com/google/go-github/v62/github"
+)
+
+// Advisory is a published repository security advisory.
+type Advisory struct {
+	GHSAID   string `json:"ghsaID" yaml:"ghsaID"`
+	CVEID    string `json:"cveID,omitempty" yaml:"cveID,omitempty"`
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Summary  string `json:"summary" yaml:"summary"`
+	URL      string `json:"url" yaml:"url"`
+}
+
+// Markdown returns the advisory as a Markdown bullet.
+func (a Advisory) Markdown() string {
+	var details []string
+	if len(a.CVEID) != 0 {
+		details = append(details, a.CVEID)
+	}
+	if len(a.Severity) != 0 {
+		details = append(details, "severity: "+a.Severity)
+	}
+	text := fmt.Sprintf("* [%s](%s)", a.GHSAID, a.URL)
+	if len(details) != 0 {
+		text += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+	return text + ": " + a.Summary
+}
+
+// SecurityAdvisories returns the published security advisories of owner/repo
+// that list version, e.g. v1.16.1, as one of their patched versions.
+func SecurityAdvisories(ctx context.Context, ghClient *gh.Client, owner, repo, version string) ([]Advisory, error) {
+	var advisories []Advisory
+	opts := &gh.ListRepositorySecurityAdvisoriesOptions{
+		State:             "published",
+		ListCursorOptions: gh.ListCursorOptions{PerPage: 100},
+	}
+	for {
+		advs, resp, err := ghClient.SecurityAdvisories.ListRepositorySecurityAdvisories(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list security advisories: %w", err)
+		}
+		for _, adv := range advs {
+			if adv.WithdrawnAt != nil || !patchesVersion(adv, version) {
+				continue
+			}
+			advisories = append(advisories, Advisory{
+				GHSAID:   adv.GetGHSAID(),
+				CVEID:    adv.GetCVEID(),
+				Severity: adv.GetSeverity(),
+				Summary:  strings.TrimSpace(adv.GetSummary()),
+				URL:      adv.GetHTMLURL(),
+			})
+		}
+		if resp.After == "" {
+			break
+		}
+		opts.After = resp.After
+	}
+	sort.Slice(advisories, func(i, j int) bool {
+		return advisories[i].GHSAID < advisories[j].GHSAID
+	})
+	return advisories, nil
+}
+
+// patchesVersion returns true if version is one of the patched versions of
+// adv. Ranges, such as ">= 1.16.0", are ignored since the advisory was only
+// fixed by the lowest version of the range.
+func patchesVersion(adv *gh.SecurityAdvisory, version string) bool {
+	version = strings.TrimPrefix(version, "v")
+	for _, vuln := range adv.Vulnerabilities {
+		patched := strings.Split(vuln.GetPatchedVersions(), ",")
+		if vuln.FirstPatchedVersion != nil {
+			patched = append(patched, vuln.FirstPatchedVersion.GetIdentifier())
+		}
+		for _, v := range patched {
+			v = strings.TrimPrefix(strings.TrimSpace(v), "=")
+			if strings.TrimPrefix(strings.TrimSpace(v), "v") == version {
+				return true
+			}
+		}
+	}
+	return false
+}
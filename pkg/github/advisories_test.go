@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	gh "github.com/google/go-github/v62/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityAdvisories(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/cilium/cilium/security-advisories", r.URL.Path)
+		assert.Equal(t, "published", r.URL.Query().Get("state"))
+		io.WriteString(w, `[
+			{
+				"ghsa_id": "GHSA-bbbb", "cve_id": "CVE-2024-2", "severity": "high",
+				"summary": "Policy bypass", "html_url": "https://github.com/cilium/cilium/security/advisories/GHSA-bbbb",
+				"vulnerabilities": [{"patched_versions": "1.14.14, 1.15.8, 1.16.1"}]
+			},
+			{
+				"ghsa_id": "GHSA-aaaa", "severity": "low",
+				"summary": "Information leak", "html_url": "https://github.com/cilium/cilium/security/advisories/GHSA-aaaa",
+				"vulnerabilities": [{"patched_versions": ">= 1.16.0", "first_patched_version": {"identifier": "v1.16.1"}}]
+			},
+			{
+				"ghsa_id": "GHSA-cccc", "summary": "Fixed in a range",
+				"vulnerabilities": [{"patched_versions": ">= 1.16.0"}]
+			},
+			{
+				"ghsa_id": "GHSA-dddd", "summary": "Withdrawn", "withdrawn_at": "2024-08-01T00:00:00Z",
+				"vulnerabilities": [{"patched_versions": "1.16.1"}]
+			}
+		]`)
+	}))
+	defer srv.Close()
+
+	client := gh.NewClient(srv.Client())
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	advisories, err := SecurityAdvisories(context.Background(), client, "cilium", "cilium", "v1.16.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []Advisory{
+		{
+			GHSAID:   "GHSA-aaaa",
+			Severity: "low",
+			Summary:  "Information leak",
+			URL:      "https://github.com/cilium/cilium/security/advisories/GHSA-aaaa",
+		},
+		{
+			GHSAID:   "GHSA-bbbb",
+			CVEID:    "CVE-2024-2",
+			Severity: "high",
+			Summary:  "Policy bypass",
+			URL:      "https://github.com/cilium/cilium/security/advisories/GHSA-bbbb",
+		},
+	}, advisories)
+	assert.Equal(t, "* [GHSA-bbbb](https://github.com/cilium/cilium/security/advisories/GHSA-bbbb) (CVE-2024-2, severity: high): Policy bypass", advisories[1].Markdown())
+}
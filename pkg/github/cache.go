@@ -0,0 +1,336 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a GitHub API response stored on disk.
+type cacheEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Stored is when the response was cached. It is only used for GraphQL
+	// responses, which can't be revalidated.
+	Stored time.Time `json:",omitzero"`
+}
+
+// response returns the cached response to req.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	// Tell go-github to not update its rate limits from this response.
+	header.Set("X-From-Cache", "1")
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// DefaultGraphQLCacheTTL is how long GraphQL responses are cached by default.
+const DefaultGraphQLCacheTTL = time.Hour
+
+// responseCache stores the GitHub REST API responses that carry an ETag or a
+// Last-Modified header so that they can be revalidated with conditional
+// requests, which don't count against the rate limit. GraphQL responses
+// can't be revalidated: they are stored for graphQLTTL and then fetched
+// again.
+var responseCache = struct {
+	sync.Mutex
+	dir        string
+	disabled   bool
+	graphQLTTL time.Duration
+	hits       int
+	misses     int
+}{graphQLTTL: DefaultGraphQLCacheTTL}
+
+// DefaultCacheDir returns the directory where GitHub API responses are
+// cached by default.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cilium-release", "github")
+}
+
+// ConfigureCache sets the directory in which the responses of all clients
+// returned by NewClient and NewGQLClient are cached. The cache is disabled if
+// dir is empty.
+func ConfigureCache(dir string) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	responseCache.dir = dir
+	responseCache.disabled = len(dir) == 0
+}
+
+// ConfigureGraphQLCacheTTL sets how long GraphQL responses are served from the
+// cache without asking GitHub. GraphQL responses are not cached if ttl is 0.
+func ConfigureGraphQLCacheTTL(ttl time.Duration) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	responseCache.graphQLTTL = ttl
+}
+
+func graphQLCacheTTL() time.Duration {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	return responseCache.graphQLTTL
+}
+
+var (
+	// cacheSubdirRegex and cacheFileRegex match the names of the directories
+	// and files written by storeCacheEntry.
+	cacheSubdirRegex = regexp.MustCompile(`^[0-9a-f]{2}$`)
+	cacheFileRegex   = regexp.MustCompile(`^([0-9a-f]{64}\.json|\.tmp-[0-9]+)$`)
+)
+
+// ClearCache removes all cached responses from dir. Only the files written by
+// the cache are removed, and nothing is removed if dir contains anything else
+// than cache directories, in case it was set to the wrong directory.
+func ClearCache(dir string) error {
+	if len(dir) == 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to clear cache %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !cacheSubdirRegex.MatchString(entry.Name()) {
+			return fmt.Errorf("refusing to clear cache %s: %s is not a cache entry", dir, entry.Name())
+		}
+	}
+	for _, entry := range entries {
+		subdir := filepath.Join(dir, entry.Name())
+		files, err := os.ReadDir(subdir)
+		if err != nil {
+			return fmt.Errorf("unable to clear cache %s: %w", dir, err)
+		}
+		for _, file := range files {
+			if file.Type().IsRegular() && cacheFileRegex.MatchString(file.Name()) {
+				if err := os.Remove(filepath.Join(subdir, file.Name())); err != nil {
+					return fmt.Errorf("unable to clear cache %s: %w", dir, err)
+				}
+			}
+		}
+		// Directories containing other files are left as is.
+		os.Remove(subdir)
+	}
+	return nil
+}
+
+// PrintCacheStats writes the number of cache hits and misses into w. Nothing
+// is written if the cache was not used.
+func PrintCacheStats(w io.Writer) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	if responseCache.hits == 0 && responseCache.misses == 0 {
+		return
+	}
+	fmt.Fprintf(w, "GitHub API cache: %d hits, %d misses (%s)\n", responseCache.hits, responseCache.misses, responseCache.dir)
+}
+
+func cacheDir() (string, bool) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	if responseCache.disabled {
+		return "", false
+	}
+	if len(responseCache.dir) == 0 {
+		responseCache.dir = DefaultCacheDir()
+	}
+	return responseCache.dir, len(responseCache.dir) != 0
+}
+
+func countCacheLookup(hit bool) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	if hit {
+		responseCache.hits++
+	} else {
+		responseCache.misses++
+	}
+}
+
+// cacheTransport serves GitHub REST API responses from the on-disk cache after
+// revalidating them with GitHub, and GraphQL responses for as long as their
+// TTL.
+type cacheTransport struct {
+	base http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dir, enabled := cacheDir()
+	if !enabled || !isIdempotent(req) {
+		return t.base.RoundTrip(req)
+	}
+	key, err := cacheKey(req)
+	if err != nil {
+		return t.base.RoundTrip(req)
+	}
+	file := filepath.Join(dir, key[:2], key+".json")
+	if isGraphQL(req) {
+		return t.roundTripGraphQL(req, file)
+	}
+
+	entry, _ := loadCacheEntry(file)
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		countCacheLookup(true)
+		return entry.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK ||
+		(resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	countCacheLookup(false)
+	storeCacheEntry(file, &cacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	})
+	return resp, nil
+}
+
+// roundTripGraphQL serves the GraphQL query req from file if it was cached
+// less than the GraphQL TTL ago. Responses with errors, e.g. rate limits or
+// PRs that could not be resolved, are not cached.
+func (t *cacheTransport) roundTripGraphQL(req *http.Request, file string) (*http.Response, error) {
+	ttl := graphQLCacheTTL()
+	if ttl <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	if entry, _ := loadCacheEntry(file); entry != nil && time.Since(entry.Stored) < ttl {
+		countCacheLookup(true)
+		return entry.response(req), nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	countCacheLookup(false)
+	if !bytes.Contains(body, []byte(`"errors"`)) {
+		storeCacheEntry(file, &cacheEntry{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       body,
+			Stored:     time.Now(),
+		})
+	}
+	return resp, nil
+}
+
+// cacheKey identifies a request by its method, URL, the media type it accepts
+// and, for GraphQL queries, its body.
+func cacheKey(req *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n%s\n", req.Method, req.URL.String(), req.Header.Get("Accept"))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func loadCacheEntry(file string) (*cacheEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// storeCacheEntry writes entry into file. Failing to cache a response is not
+// fatal so errors are ignored.
+func storeCacheEntry(file string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), file)
+}
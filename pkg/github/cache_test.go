@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheTransport(t *testing.T) {
+	ConfigureCache(t.TempDir())
+	defer ConfigureCache("")
+
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, `{"number": 1}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &cacheTransport{base: http.DefaultTransport}}
+	get := func() (*http.Response, string) {
+		resp, err := client.Get(srv.URL + "/repos/cilium/cilium/pulls/1")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := get()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"number": 1}`, body)
+	assert.Empty(t, resp.Header.Get("X-From-Cache"))
+
+	resp, body = get()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"number": 1}`, body)
+	assert.Equal(t, "1", resp.Header.Get("X-From-Cache"))
+
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified)
+
+	// Disabling the cache sends unconditional requests.
+	ConfigureCache("")
+	resp, _ = get()
+	assert.Empty(t, resp.Header.Get("X-From-Cache"))
+	assert.Equal(t, 1, notModified)
+}
+
+func TestCacheTransportGraphQL(t *testing.T) {
+	ConfigureCache(t.TempDir())
+	defer ConfigureCache("")
+	defer ConfigureGraphQLCacheTTL(DefaultGraphQLCacheTTL)
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"number":2`) {
+			io.WriteString(w, `{"data": {"repository": {"pullRequest": null}}, "errors": [{"message": "Could not resolve to a PullRequest with the number of 2."}]}`)
+			return
+		}
+		io.WriteString(w, `{"data": {"repository": {"pullRequest": {"title": "Fix a crash"}}}}`)
+	}))
+	defer srv.Close()
+
+	client := githubv4.NewEnterpriseClient(srv.URL+"/api/graphql", &http.Client{Transport: &cacheTransport{base: http.DefaultTransport}})
+	query := func(prNumber int) string {
+		var q struct {
+			Repository struct {
+				PullRequest *struct {
+					Title githubv4.String
+				} `graphql:"pullRequest(number: $number)"`
+			} `graphql:"repository(owner: \"cilium\", name: \"cilium\")"`
+		}
+		err := client.Query(context.Background(), &q, map[string]any{"number": githubv4.Int(prNumber)})
+		if q.Repository.PullRequest == nil {
+			return err.Error()
+		}
+		return string(q.Repository.PullRequest.Title)
+	}
+
+	// Repeated queries are served from the cache.
+	assert.Equal(t, "Fix a crash", query(1))
+	assert.Equal(t, "Fix a crash", query(1))
+	assert.Equal(t, 1, requests)
+
+	// Responses with errors are not cached.
+	assert.Contains(t, query(2), "Could not resolve")
+	assert.Contains(t, query(2), "Could not resolve")
+	assert.Equal(t, 3, requests)
+
+	// Nor is anything once the TTL is 0.
+	ConfigureGraphQLCacheTTL(0)
+	assert.Equal(t, "Fix a crash", query(1))
+	assert.Equal(t, 4, requests)
+}
+
+func TestClearCache(t *testing.T) {
+	dir := t.TempDir()
+	key := strings.Repeat("ab", 32)
+	storeCacheEntry(filepath.Join(dir, key[:2], key+".json"), &cacheEntry{StatusCode: http.StatusOK})
+
+	assert.NoError(t, ClearCache(dir))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoError(t, ClearCache(filepath.Join(dir, "does-not-exist")))
+
+	// A directory that isn't a cache is left as is.
+	storeCacheEntry(filepath.Join(dir, key[:2], key+".json"), &cacheEntry{StatusCode: http.StatusOK})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".bashrc"), nil, 0644))
+	assert.Error(t, ClearCache(dir))
+	_, err = os.Stat(filepath.Join(dir, key[:2], key+".json"))
+	assert.NoError(t, err)
+}
@@ -18,11 +18,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"strings"
 
 	gh "github.com/google/go-github/v62/github"
+	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 )
 
@@ -54,15 +56,27 @@ func Token() string {
 	return ghToken
 }
 
-func NewClient() *gh.Client {
-	return gh.NewClient(
-		oauth2.NewClient(
-			context.Background(),
-			oauth2.StaticTokenSource(
-				&oauth2.Token{
-					AccessToken: Token(),
-				},
-			),
+// newHTTPClient returns an authenticated HTTP client that waits for, and
+// retries requests on, GitHub rate limits and caches the responses on disk.
+func newHTTPClient() *http.Client {
+	client := oauth2.NewClient(
+		context.Background(),
+		oauth2.StaticTokenSource(
+			&oauth2.Token{
+				AccessToken: Token(),
+			},
 		),
 	)
+	client.Transport = &cacheTransport{
+		base: &rateLimitTransport{base: client.Transport},
+	}
+	return client
+}
+
+func NewClient() *gh.Client {
+	return gh.NewClient(newHTTPClient())
+}
+
+func NewGQLClient() *githubv4.Client {
+	return githubv4.NewClient(newHTTPClient())
 }
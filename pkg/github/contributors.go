@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// gqlSearch was derived from
+//
+//	search(query: $query, type: ISSUE, first: $first) {
+//	  nodes { ... on PullRequest { number mergedAt } }
+//	}
+type gqlSearch struct {
+	Nodes []struct {
+		PullRequest struct {
+			Number   githubv4.Int
+			MergedAt githubv4.DateTime
+		} `graphql:"... on PullRequest"`
+	}
+}
+
+// firstMergedPRCandidates is the number of PRs, created first, among which
+// the first merged PR of an author is looked up. The search API can't sort
+// PRs by merge time, and a PR may be merged long after being created.
+const firstMergedPRCandidates = 10
+
+// FirstMergedPRs returns the number of the first merged PR of each author in
+// owner/repo, or 0 if an author doesn't have any. It is the PR merged first
+// among the firstMergedPRCandidates PRs of the author created first.
+func FirstMergedPRs(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, authors []string) (map[string]int, error) {
+	firstPRs := make(map[string]int, len(authors))
+	for start := 0; start < len(authors); start += graphQLBatchSize {
+		batch := authors[start:min(start+graphQLBatchSize, len(authors))]
+		results, err := aliasedQuery[gqlSearch](ctx, ghGQLClient, "", nil, batch, func(i int, author string) string {
+			query := fmt.Sprintf("repo:%s/%s is:pr is:merged author:%s sort:created-asc", owner, repo, author)
+			return fmt.Sprintf("a%d: search(query: %q, type: ISSUE, first: %d)", i, query, firstMergedPRCandidates)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range results {
+			firstPRs[batch[i]] = 0
+			if result == nil {
+				continue
+			}
+			var firstMergedAt time.Time
+			for _, node := range result.Nodes {
+				pr := node.PullRequest
+				if firstMergedAt.IsZero() || pr.MergedAt.Before(firstMergedAt) {
+					firstPRs[batch[i]], firstMergedAt = int(pr.Number), pr.MergedAt.Time
+				}
+			}
+		}
+	}
+	return firstPRs, nil
+}
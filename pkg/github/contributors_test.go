@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstMergedPRs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ Query string }
+		json.Unmarshal(body, &req)
+		if !strings.Contains(req.Query, `a0: search(query: "repo:cilium/cilium is:pr is:merged author:alice sort:created-asc", type: ISSUE, first: 10)`) ||
+			!strings.Contains(req.Query, `a1: search(query: "repo:cilium/cilium is:pr is:merged author:bob sort:created-asc", type: ISSUE, first: 10)`) {
+			t.Errorf("unexpected query: %s", req.Query)
+		}
+		io.WriteString(w, `{"data": {
+			"a0": {"nodes": [
+				{"number": 40, "mergedAt": "2024-03-01T00:00:00Z"},
+				{"number": 42, "mergedAt": "2024-01-01T00:00:00Z"},
+				{"number": 45, "mergedAt": "2024-02-01T00:00:00Z"}
+			]},
+			"a1": {"nodes": []}
+		}}`)
+	}))
+	defer srv.Close()
+
+	firstPRs, err := FirstMergedPRs(context.Background(), githubv4.NewEnterpriseClient(srv.URL, srv.Client()),
+		"cilium", "cilium", []string{"alice", "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"alice": 42, "bob": 0}, firstPRs)
+}
@@ -0,0 +1,346 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/shurcooL/githubv4"
+
+	"github.com/cilium/release/pkg/types"
+)
+
+// graphQLBatchSize is the number of commits, or PRs, fetched with a single
+// GraphQL query.
+const graphQLBatchSize = 25
+
+// gqlPullRequest was derived from
+//
+//	pullRequest {
+//	  number
+//	  state
+//	  title
+//	  body
+//	  id
+//	  author { login }
+//	  labels(first: 100) { nodes { name } }
+//	}
+type gqlPullRequest struct {
+	Number githubv4.Int
+	State  githubv4.PullRequestState
+	Title  githubv4.String
+	Body   githubv4.String
+	ID     githubv4.ID
+	Author struct {
+		Login githubv4.String
+	}
+	Labels struct {
+		Nodes []struct {
+			Name githubv4.String
+		}
+	} `graphql:"labels(first: 100)"`
+}
+
+func (pr *gqlPullRequest) labels() []string {
+	var lbls []string
+	for _, lbl := range pr.Labels.Nodes {
+		lbls = append(lbls, string(lbl.Name))
+	}
+	return lbls
+}
+
+func (pr *gqlPullRequest) nodeID() string {
+	id, _ := pr.ID.(string)
+	return id
+}
+
+// gqlCommit was derived from
+//
+//	object(expression: $sha) {
+//	  ... on Commit {
+//	    message
+//	    associatedPullRequests(first: 10) { nodes { ...pullRequest } }
+//	  }
+//	}
+type gqlCommit struct {
+	Commit struct {
+		Message                githubv4.String
+		AssociatedPullRequests struct {
+			Nodes []gqlPullRequest
+		} `graphql:"associatedPullRequests(first: 10)"`
+	} `graphql:"... on Commit"`
+}
+
+// batchQuery runs a GraphQL query under the repository owner/repo with one
+// aliased field of type T per element of args. The field is built by calling
+// field with the element's index and value. It returns one result per
+// element of args, which is nil if GitHub could not resolve the field.
+func batchQuery[T any, A any](ctx context.Context, client *githubv4.Client, owner, repo string, args []A, field func(i int, arg A) string) ([]*T, error) {
+	variables := map[string]any{
+		"owner": githubv4.String(owner),
+		"repo":  githubv4.String(repo),
+	}
+	return aliasedQuery[T](ctx, client, "repository(owner: $owner, name: $repo)", variables, args, field)
+}
+
+// aliasedQuery is like batchQuery but the aliased fields are queried under
+// the given root field, or at the top level of the query if root is empty.
+func aliasedQuery[T any, A any](ctx context.Context, client *githubv4.Client, root string, variables map[string]any, args []A, field func(i int, arg A) string) ([]*T, error) {
+	fields := make([]reflect.StructField, len(args))
+	for i, arg := range args {
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeOf((*T)(nil)),
+			Tag:  reflect.StructTag(fmt.Sprintf("graphql:%q", field(i, arg))),
+		}
+	}
+	query := reflect.New(reflect.StructOf(fields))
+	if len(root) != 0 {
+		query = reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "Root",
+			Type: reflect.StructOf(fields),
+			Tag:  reflect.StructTag(fmt.Sprintf("graphql:%q", root)),
+		}}))
+	}
+
+	err := client.Query(ctx, query.Interface(), variables)
+	// Nonexistent PRs are reported as errors but their fields are still
+	// returned as null alongside the remaining data. Callers report them.
+	// Any other error, e.g. for a nonexistent repository, is fatal.
+	if err != nil && !onlyUnresolvedPullRequests(err) {
+		return nil, err
+	}
+
+	results := query.Elem()
+	if len(root) != 0 {
+		results = results.Field(0)
+	}
+	values := make([]*T, len(args))
+	for i := range args {
+		values[i] = results.Field(i).Interface().(*T)
+	}
+	return values, nil
+}
+
+// unresolvedPullRequestError is the prefix of the GraphQL errors returned for
+// PR numbers that don't exist in the repository.
+const unresolvedPullRequestError = "Could not resolve to a PullRequest "
+
+// onlyUnresolvedPullRequests returns true if err is a list of GraphQL errors
+// that are all about nonexistent PRs.
+func onlyUnresolvedPullRequests(err error) bool {
+	// The githubv4 client doesn't export its list of errors, whose Error
+	// method only returns the first one.
+	errs := reflect.ValueOf(err)
+	if errs.Kind() != reflect.Slice || errs.Type().Elem().Kind() != reflect.Struct {
+		return false
+	}
+	if _, ok := errs.Type().Elem().FieldByName("Message"); !ok || errs.Len() == 0 {
+		return false
+	}
+	for i := range errs.Len() {
+		if !strings.HasPrefix(errs.Index(i).FieldByName("Message").String(), unresolvedPullRequestError) {
+			return false
+		}
+	}
+	return true
+}
+
+// GeneratePatchReleaseGraphQL is the equivalent of GeneratePatchRelease but
+// fetches the PRs associated with the commits, and their upstream PRs, in
+// batches with the GraphQL API.
+// In case of an error, a list of non-processed commits will be returned.
+func GeneratePatchReleaseGraphQL(
+	ctx context.Context,
+	ghGQLClient *githubv4.Client,
+	owner string,
+	repo string,
+	bar *progressbar.ProgressBar,
+	printer func(msg string),
+	backportPRs types.BackportPRs,
+	listOfPRs types.PullRequests,
+	nodeIDs types.NodeIDs,
+	commits []string,
+) (
+	types.BackportPRs,
+	types.PullRequests,
+	types.NodeIDs,
+	[]string,
+	error,
+) {
+
+	links := newUpstreamLinks()
+	for start := 0; start < len(commits); start += graphQLBatchSize {
+		end := min(start+graphQLBatchSize, len(commits))
+		batch := commits[start:end]
+
+		gqlCommits, err := batchQuery[gqlCommit](ctx, ghGQLClient, owner, repo, batch, func(i int, sha string) string {
+			return fmt.Sprintf("c%d: object(expression: %q)", i, sha)
+		})
+		if err != nil {
+			return backportPRs, listOfPRs, nodeIDs, commits[start:], err
+		}
+		// Resolve the upstream commits of backports and the commits
+		// reverted by this batch to their PRs.
+		var upstreamSHAs, revertedSHAs []string
+		for _, gqlCommit := range gqlCommits {
+			if gqlCommit != nil {
+				upstreamSHAs = append(upstreamSHAs, getUpstreamCommits(string(gqlCommit.Commit.Message))...)
+				revertedSHAs = append(revertedSHAs, getRevertedCommits(string(gqlCommit.Commit.Message))...)
+			}
+		}
+		upstreamSHAs = slices.Compact(slices.Sorted(slices.Values(upstreamSHAs)))
+		commitPRs, err := resolveCommitPRs(ctx, ghGQLClient, owner, repo, upstreamSHAs, revertedSHAs)
+		if err != nil {
+			return backportPRs, listOfPRs, nodeIDs, commits[start:], err
+		}
+		for _, sha := range upstreamSHAs {
+			if _, ok := commitPRs[sha]; !ok {
+				printer(fmt.Sprintf("\nWARNING: PR not found for upstream commit %s!\n", sha))
+			}
+		}
+
+		// Backport PRs found in this batch, only stored once all their
+		// upstream PRs were retrieved so that the batch can be resumed.
+		newBackportPRs := map[int]gqlPullRequest{}
+		// Upstream PRs found in the trailers of the commits of this batch,
+		// indexed by backport PR.
+		trailerPRs := map[int][]int{}
+		for i, gqlCommit := range gqlCommits {
+			foundPR := false
+			if gqlCommit != nil {
+				message := string(gqlCommit.Commit.Message)
+				commitTrailerPRs := commitPRNumbers(getUpstreamCommits(message), commitPRs)
+				for _, pr := range gqlCommit.Commit.AssociatedPullRequests.Nodes {
+					prNumber := int(pr.Number)
+					_, ok := listOfPRs[prNumber]
+					_, ok2 := backportPRs[prNumber]
+					_, ok3 := newBackportPRs[prNumber]
+					if ok2 || ok3 {
+						trailerPRs[prNumber] = mergePRNumbers(trailerPRs[prNumber], commitTrailerPRs)
+					}
+					if ok || ok2 || ok3 {
+						foundPR = true
+						continue
+					}
+					if pr.State == githubv4.PullRequestStateOpen {
+						continue
+					}
+					foundPR = true
+					// Backports whose body was edited may have lost their
+					// upstream-prs block but their commits still reference
+					// the upstream commits.
+					if upstreamPRs := getUpstreamPRs(string(pr.Body)); upstreamPRs != nil || len(commitTrailerPRs) != 0 {
+						links.addBody(prNumber, upstreamPRs)
+						trailerPRs[prNumber] = commitTrailerPRs
+						newBackportPRs[prNumber] = pr
+						continue
+					}
+					lbls := pr.labels()
+					listOfPRs[prNumber] = types.PullRequest{
+						ReleaseNote:      getReleaseNote(string(pr.Title), string(pr.Body)),
+						Title:            string(pr.Title),
+						ReleaseLabel:     getReleaseLabel(lbls),
+						AuthorName:       string(pr.Author.Login),
+						BackportBranches: getBackportBranches(lbls),
+						Labels:           lbls,
+						UpgradeNotes:     getUpgradeNotes(string(pr.Title), string(pr.Body), lbls),
+						Reverts: mergePRNumbers(
+							getRevertedPRs(owner, repo, string(pr.Title), string(pr.Body)),
+							commitPRNumbers(getRevertedCommits(message), commitPRs),
+						),
+					}
+					nodeIDs[prNumber] = pr.nodeID()
+				}
+			}
+			if !foundPR {
+				printer(fmt.Sprintf("\nWARNING: PR not found for commit %s!\n", batch[i]))
+			}
+		}
+
+		// The upstream PRs to add to each backport PR: all of them for the
+		// new backport PRs and, for the ones found in a previous batch, those
+		// only referenced by the trailers of this batch.
+		pendingPRs := map[int][]int{}
+		for prNumber, upstreamPRNumbers := range trailerPRs {
+			links.addTrailers(prNumber, upstreamPRNumbers)
+			if _, ok := newBackportPRs[prNumber]; ok {
+				continue
+			}
+			for _, upstreamPRNumber := range upstreamPRNumbers {
+				if _, ok := backportPRs[prNumber][upstreamPRNumber]; !ok {
+					pendingPRs[prNumber] = append(pendingPRs[prNumber], upstreamPRNumber)
+				}
+			}
+		}
+		for prNumber := range newBackportPRs {
+			pendingPRs[prNumber] = links.upstreamPRs(prNumber)
+		}
+
+		upstreamPRs, err := fetchUpstreamPRs(ctx, ghGQLClient, owner, repo, printer, pendingPRs)
+		if err != nil {
+			return backportPRs, listOfPRs, nodeIDs, commits[start:], err
+		}
+		for prNumber, upstreamPRNumbers := range pendingPRs {
+			if _, ok := backportPRs[prNumber]; !ok {
+				backportPRs[prNumber] = map[int]types.PullRequest{}
+			}
+			for _, upstreamPRNumber := range upstreamPRNumbers {
+				upstreamPR, ok := upstreamPRs[upstreamPRNumber]
+				if !ok {
+					continue
+				}
+				lbls := upstreamPR.labels()
+				backportPRs[prNumber][upstreamPRNumber] = types.PullRequest{
+					ReleaseNote:  getReleaseNote(string(upstreamPR.Title), string(upstreamPR.Body)),
+					Title:        string(upstreamPR.Title),
+					ReleaseLabel: getReleaseLabel(lbls),
+					AuthorName:   string(upstreamPR.Author.Login),
+					Labels:       lbls,
+					Reverts:      getRevertedPRs(owner, repo, string(upstreamPR.Title), string(upstreamPR.Body)),
+					UpgradeNotes: getUpgradeNotes(string(upstreamPR.Title), string(upstreamPR.Body), lbls),
+				}
+				if pr, ok := newBackportPRs[prNumber]; ok {
+					nodeIDs[prNumber] = pr.nodeID()
+				}
+				nodeIDs[upstreamPRNumber] = upstreamPR.nodeID()
+			}
+		}
+		bar.Add(len(batch))
+	}
+	links.report(printer)
+	return backportPRs, listOfPRs, nodeIDs, nil, nil
+}
+
+// fetchUpstreamPRs returns all upstream PRs in the given map of backport PRs
+// to upstream PRs, indexed by PR number.
+func fetchUpstreamPRs(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, printer func(msg string), backportPRs map[int][]int) (map[int]gqlPullRequest, error) {
+	var prNumbers []int
+	seen := map[int]struct{}{}
+	for _, upstreamPRNumbers := range backportPRs {
+		for _, upstreamPRNumber := range upstreamPRNumbers {
+			if _, ok := seen[upstreamPRNumber]; ok {
+				continue
+			}
+			seen[upstreamPRNumber] = struct{}{}
+			prNumbers = append(prNumbers, upstreamPRNumber)
+		}
+	}
+
+	upstreamPRs, err := fetchPullRequests(ctx, ghGQLClient, owner, repo, prNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for _, prNumber := range prNumbers {
+		if _, ok := upstreamPRs[prNumber]; !ok {
+			printer(fmt.Sprintf("\nWARNING: PR not found %d!\n", prNumber))
+		}
+	}
+	return upstreamPRs, nil
+}
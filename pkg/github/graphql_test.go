@@ -0,0 +1,284 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/types"
+)
+
+func TestGeneratePatchReleaseGraphQL(t *testing.T) {
+	const (
+		commitsResponse = `{"data": {"repository": {
+			"c0": {"associatedPullRequests": {"nodes": [{
+				"number": 10, "state": "MERGED", "title": "v1.16 backports", "id": "PR_10",
+				"body": "` + "```upstream-prs\\n1 2\\n```" + `",
+				"author": {"login": "backporter"},
+				"labels": {"nodes": [{"name": "kind/backports"}]}
+			}]}},
+			"c1": {"associatedPullRequests": {"nodes": [{
+				"number": 3, "state": "MERGED", "title": "Add a feature", "id": "PR_3",
+				"body": "` + "```release-note\\nAdd a great feature\\n```" + `",
+				"author": {"login": "bob"},
+				"labels": {"nodes": [{"name": "release-note/minor"}, {"name": "backport-done/1.15"}]}
+			}]}},
+			"c2": {"associatedPullRequests": {"nodes": [{
+				"number": 4, "state": "OPEN", "title": "Still open", "id": "PR_4", "body": "",
+				"author": {"login": "carol"}, "labels": {"nodes": []}
+			}]}}
+		}}}`
+		upstreamResponse = `{"data": {"repository": {
+			"p0": {
+				"number": 1, "state": "MERGED", "title": "Fix a crash", "id": "PR_1", "body": "",
+				"author": {"login": "alice"},
+				"labels": {"nodes": [{"name": "release-note/bug"}]}
+			},
+			"p1": null
+		}}, "errors": [{"message": "Could not resolve to a PullRequest with the number of 2."}]}`
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ Query string }
+		json.Unmarshal(body, &req)
+		switch {
+		case strings.Contains(req.Query, `c0: object(expression: "aaa")`):
+			io.WriteString(w, commitsResponse)
+		case strings.Contains(req.Query, "p0: pullRequest(number: 1)"):
+			io.WriteString(w, upstreamResponse)
+		default:
+			t.Errorf("unexpected query: %s", req.Query)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var warnings []string
+	backportPRs, prs, nodeIDs, left, err := GeneratePatchReleaseGraphQL(
+		context.Background(),
+		githubv4.NewEnterpriseClient(srv.URL, srv.Client()),
+		"cilium", "cilium",
+		progressbar.DefaultSilent(3),
+		func(msg string) { warnings = append(warnings, strings.TrimSpace(msg)) },
+		types.BackportPRs{}, types.PullRequests{}, types.NodeIDs{},
+		[]string{"aaa", "bbb", "ccc"},
+	)
+	assert.NoError(t, err)
+	assert.Nil(t, left)
+	assert.Equal(t, types.BackportPRs{
+		10: {
+			1: {
+				ReleaseNote:  "Fix a crash",
+				ReleaseLabel: "release-note/bug",
+				AuthorName:   "alice",
+				Labels:       []string{"release-note/bug"},
+				Title:        "Fix a crash",
+			},
+		},
+	}, backportPRs)
+	assert.Equal(t, types.PullRequests{
+		3: {
+			ReleaseNote:      "Add a great feature",
+			ReleaseLabel:     "release-note/minor",
+			AuthorName:       "bob",
+			BackportBranches: []string{"backport-done/1.15"},
+			Labels:           []string{"release-note/minor", "backport-done/1.15"},
+			Title:            "Add a feature",
+		},
+	}, prs)
+	assert.Equal(t, types.NodeIDs{1: "PR_1", 3: "PR_3", 10: "PR_10"}, nodeIDs)
+	assert.Equal(t, []string{
+		"WARNING: PR not found for commit ccc!",
+		"WARNING: PR not found 2!",
+	}, warnings)
+}
+
+func TestGeneratePatchReleaseGraphQLUpstreamCommits(t *testing.T) {
+	const (
+		commitsResponse = `{"data": {"repository": {
+			"c0": {
+				"message": "Fix a crash\n\n[ upstream commit 1111111 ]\n\n[ upstream commit 2222222 ]",
+				"associatedPullRequests": {"nodes": [{
+					"number": 10, "state": "MERGED", "title": "v1.16 backports", "id": "PR_10",
+					"body": "` + "```upstream-prs\\n1\\n```" + `",
+					"author": {"login": "backporter"}, "labels": {"nodes": []}
+				}]}
+			},
+			"c1": {
+				"message": "Add a feature\n\n[ upstream commit 3333333 ]",
+				"associatedPullRequests": {"nodes": [{
+					"number": 11, "state": "MERGED", "title": "v1.16 backports", "id": "PR_11",
+					"body": "Edited body",
+					"author": {"login": "backporter"}, "labels": {"nodes": []}
+				}]}
+			}
+		}}}`
+		upstreamCommitsResponse = `{"data": {"repository": {
+			"c0": {"associatedPullRequests": {"nodes": [{"number": 1, "state": "MERGED", "body": ""}]}},
+			"c1": {"associatedPullRequests": {"nodes": [{"number": 2, "state": "MERGED", "body": ""}]}},
+			"c2": {"associatedPullRequests": {"nodes": [
+				{"number": 12, "state": "OPEN", "body": ""},
+				{"number": 3, "state": "MERGED", "body": ""}
+			]}}
+		}}}`
+	)
+	prFieldRegex := regexp.MustCompile(`p(\d+): pullRequest\(number: (\d+)\)`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ Query string }
+		json.Unmarshal(body, &req)
+		switch {
+		case strings.Contains(req.Query, `c0: object(expression: "aaa")`):
+			io.WriteString(w, commitsResponse)
+		case strings.Contains(req.Query, `c0: object(expression: "1111111")`):
+			io.WriteString(w, upstreamCommitsResponse)
+		case prFieldRegex.MatchString(req.Query):
+			var fields []string
+			for _, m := range prFieldRegex.FindAllStringSubmatch(req.Query, -1) {
+				fields = append(fields, fmt.Sprintf(`"p%s": {"number": %s, "state": "MERGED", "title": "Upstream %s", "id": "PR_%s", "body": "", "author": {"login": "alice"}, "labels": {"nodes": []}}`, m[1], m[2], m[2], m[2]))
+			}
+			io.WriteString(w, `{"data": {"repository": {`+strings.Join(fields, ",")+`}}}`)
+		default:
+			t.Errorf("unexpected query: %s", req.Query)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var warnings []string
+	backportPRs, prs, _, left, err := GeneratePatchReleaseGraphQL(
+		context.Background(),
+		githubv4.NewEnterpriseClient(srv.URL, srv.Client()),
+		"cilium", "cilium",
+		progressbar.DefaultSilent(2),
+		func(msg string) { warnings = append(warnings, strings.TrimSpace(msg)) },
+		types.BackportPRs{}, types.PullRequests{}, types.NodeIDs{},
+		[]string{"aaa", "bbb"},
+	)
+	assert.NoError(t, err)
+	assert.Nil(t, left)
+	assert.Empty(t, prs)
+	upstreamPR := func(prNumber int) types.PullRequest {
+		return types.PullRequest{
+			ReleaseNote:  fmt.Sprintf("Upstream %d", prNumber),
+			ReleaseLabel: "release-note/none",
+			AuthorName:   "alice",
+			Title:        fmt.Sprintf("Upstream %d", prNumber),
+		}
+	}
+	assert.Equal(t, types.BackportPRs{
+		10: {1: upstreamPR(1), 2: upstreamPR(2)},
+		11: {3: upstreamPR(3)},
+	}, backportPRs)
+	assert.Equal(t, []string{
+		"WARNING: Backport PR 10: upstream PR 2 is referenced by an upstream commit trailer but missing from the upstream-prs block",
+		"WARNING: Backport PR 11 doesn't have an upstream-prs block, using upstream PRs [3] from its upstream commit trailers",
+	}, warnings)
+}
+
+func TestGeneratePatchReleaseGraphQLRevertedBackport(t *testing.T) {
+	const (
+		commitsResponse = `{"data": {"repository": {
+			"c0": {
+				"message": "Revert two fixes\n\nThis reverts commit 4444444.\nThis reverts commit 6666666.",
+				"associatedPullRequests": {"nodes": [{
+					"number": 20, "state": "MERGED", "title": "Revert two fixes", "id": "PR_20",
+					"body": "` + "```release-note\\nRevert the crash fixes\\n```" + `",
+					"author": {"login": "alice"}, "labels": {"nodes": [{"name": "release-note/bug"}]}
+				}]}
+			}
+		}}}`
+		// The reverted commits were merged by backport PRs. The first one
+		// has an upstream commit trailer, the second one doesn't.
+		revertedCommitsResponse = `{"data": {"repository": {
+			"c0": {
+				"message": "Fix a crash\n\n[ upstream commit 5555555 ]",
+				"associatedPullRequests": {"nodes": [{
+					"number": 10, "state": "MERGED", "body": "` + "```upstream-prs\\n1\\n2\\n```" + `"
+				}]}
+			},
+			"c1": {
+				"message": "Fix another crash",
+				"associatedPullRequests": {"nodes": [{
+					"number": 11, "state": "MERGED", "body": "` + "```upstream-prs\\n3\\n4\\n```" + `"
+				}]}
+			}
+		}}}`
+		upstreamCommitsResponse = `{"data": {"repository": {
+			"c0": {"associatedPullRequests": {"nodes": [{"number": 1, "state": "MERGED", "body": ""}]}}
+		}}}`
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ Query string }
+		json.Unmarshal(body, &req)
+		switch {
+		case strings.Contains(req.Query, `c0: object(expression: "aaa")`):
+			io.WriteString(w, commitsResponse)
+		case strings.Contains(req.Query, `c0: object(expression: "4444444")`):
+			io.WriteString(w, revertedCommitsResponse)
+		case strings.Contains(req.Query, `c0: object(expression: "5555555")`):
+			io.WriteString(w, upstreamCommitsResponse)
+		default:
+			t.Errorf("unexpected query: %s", req.Query)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	_, prs, _, left, err := GeneratePatchReleaseGraphQL(
+		context.Background(),
+		githubv4.NewEnterpriseClient(srv.URL, srv.Client()),
+		"cilium", "cilium",
+		progressbar.DefaultSilent(1),
+		func(msg string) {},
+		types.BackportPRs{}, types.PullRequests{}, types.NodeIDs{},
+		[]string{"aaa"},
+	)
+	assert.NoError(t, err)
+	assert.Nil(t, left)
+	// The first revert is resolved to the upstream PR, the second one to the
+	// backport PR.
+	assert.Equal(t, []int{1, 11}, prs[20].Reverts)
+}
+
+func TestFetchPullRequestsErrors(t *testing.T) {
+	var response string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, response)
+	}))
+	defer srv.Close()
+	client := githubv4.NewEnterpriseClient(srv.URL, srv.Client())
+
+	// Nonexistent PRs are left out.
+	response = `{"data": {"repository": {"p0": null, "p1": {"number": 2, "title": "Add a feature"}}},
+		"errors": [{"message": "Could not resolve to a PullRequest with the number of 1."}]}`
+	prs, err := fetchPullRequests(context.Background(), client, "cilium", "cilium", []int{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, prs, 1)
+	assert.Contains(t, prs, 2)
+
+	// A nonexistent repository is an error, even if it comes after errors
+	// about PRs.
+	response = `{"data": {"repository": null}, "errors": [
+		{"message": "Could not resolve to a PullRequest with the number of 1."},
+		{"message": "Could not resolve to a Repository with the name 'cilium/cilum'."}]}`
+	_, err = fetchPullRequests(context.Background(), client, "cilium", "cilum", []int{1, 2})
+	assert.Error(t, err)
+}
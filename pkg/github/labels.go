@@ -17,9 +17,12 @@ package github
 import (
 	"bufio"
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
 	blang_semver "github.com/blang/semver/v4"
 	gh "github.com/google/go-github/v62/github"
@@ -27,10 +30,19 @@ import (
 )
 
 const (
-	releaseNoteBlock = "```release-note"
-	upstreamPRsBlock = "```upstream-prs"
-	commentTag       = "<!--"
-	endBlock         = "```"
+	releaseNoteBlock  = "```release-note"
+	upgradeNotesBlock = "```upgrade-notes"
+	upstreamPRsBlock  = "```upstream-prs"
+	commentTag        = "<!--"
+	endBlock          = "```"
+
+	// upgradeImpactLabel marks PRs that need to be mentioned in the upgrade
+	// notes even if they don't have an upgrade-notes block.
+	upgradeImpactLabel = "upgrade-impact"
+
+	// EmbargoedLabel marks PRs fixing security issues whose advisory is not
+	// public yet. It isn't a release label, these PRs still need one.
+	EmbargoedLabel = "release-note/embargoed"
 )
 
 // Get the text between startBlock and endBlock
@@ -69,6 +81,43 @@ func textBlockBetween(body, startBlock string) string {
 	return strings.TrimSpace(strings.Join(lines[beginning+1:end], " "))
 }
 
+// markdownBlockBetween is like textBlockBetween but keeps the lines of the
+// block, without their common indentation, so that the block can contain
+// multi-line Markdown.
+func markdownBlockBetween(body, startBlock string) string {
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	var lines []string
+	inBlock := false
+	for scanner.Scan() {
+		line := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		if !inBlock {
+			inBlock = strings.TrimSpace(line) == startBlock
+			continue
+		}
+		if strings.TrimSpace(line) == endBlock {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+	for i, line := range lines {
+		if len(line) != 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
 func getUpstreamPRs(body string) []int {
 	if !strings.Contains(body, upstreamPRsBlock) {
 		return nil
@@ -129,21 +178,71 @@ func getUpstreamPRsV2(block string) []int {
 }
 
 // getReleaseNote returns the release node if it is present in the given body
-// otherwise it will fallback to the title.
+// otherwise it will fallback to the title. The Markdown structure of the
+// release note, such as lists and code blocks, is kept.
 func getReleaseNote(title, body string) string {
 	if strings.Contains(body, releaseNoteBlock) {
-		block := textBlockBetween(body, releaseNoteBlock)
+		block := markdownBlockBetween(body, releaseNoteBlock)
 		if len(block) != 0 && !strings.Contains(block, commentTag) {
-			return block
+			return unwrapMarkdown(block)
 		}
 	}
 	return strings.TrimSpace(title)
 }
 
+// markdownBlockStart matches the lines that start a new Markdown block, such
+// as a list item, instead of continuing the paragraph above them.
+var markdownBlockStart = regexp.MustCompile(`^([-*+]|\d+[.)])(\s|$)|^(#|>|\||` + endBlock + `|~~~)`)
+
+// unwrapMarkdown joins the lines of each Markdown paragraph, or list item,
+// with a space, which renders the same, so that single-line release notes
+// written over several lines stay on a single line. Code blocks are left as
+// is.
+func unwrapMarkdown(text string) string {
+	var lines []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, endBlock) || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			lines = append(lines, line)
+			continue
+		}
+		if !inCode && len(lines) != 0 && len(trimmed) != 0 && !markdownBlockStart.MatchString(trimmed) {
+			prev := strings.TrimSpace(lines[len(lines)-1])
+			if len(prev) != 0 && !strings.HasPrefix(prev, endBlock) && !strings.HasPrefix(prev, "~~~") {
+				lines[len(lines)-1] += " " + trimmed
+				continue
+			}
+		}
+		if !inCode && len(trimmed) == 0 && len(lines) != 0 && len(lines[len(lines)-1]) == 0 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getUpgradeNotes returns the upgrade notes of a PR if it has an
+// upgrade-notes block. PRs with the upgrade-impact label but no such block
+// use their release note instead. Otherwise it returns an empty string.
+func getUpgradeNotes(title, body string, lbls []string) string {
+	if strings.Contains(body, upgradeNotesBlock) {
+		block := markdownBlockBetween(body, upgradeNotesBlock)
+		if len(block) != 0 && !strings.Contains(block, commentTag) {
+			return block
+		}
+	}
+	if slices.Contains(lbls, upgradeImpactLabel) {
+		return getReleaseNote(title, body)
+	}
+	return ""
+}
+
 // getReleaseLabel returns the release label found in the slice of labels.
 func getReleaseLabel(lbls []string) string {
 	for _, lbl := range lbls {
-		if strings.HasPrefix(lbl, "release-note/") {
+		if strings.HasPrefix(lbl, "release-note/") && lbl != EmbargoedLabel {
 			return lbl
 		}
 	}
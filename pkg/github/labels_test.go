@@ -79,6 +79,45 @@ func Test_getReleaseNote(t *testing.T) {
 			},
 			want: "Pineapple pizza",
 		},
+		{
+			name: "multiple notes",
+			args: args{
+				title: "Fooo",
+				body: "```release-note\n" +
+					"- Add the `--foo` flag, which\n" +
+					"  replaces `--bar`\n" +
+					"- Fix a crash of:\n" +
+					"  * the agent\n" +
+					"  * the operator\n" +
+					"```\n",
+			},
+			want: "- Add the `--foo` flag, which replaces `--bar`\n" +
+				"- Fix a crash of:\n" +
+				"  * the agent\n" +
+				"  * the operator",
+		},
+		{
+			name: "code block",
+			args: args{
+				title: "Fooo",
+				body: "```release-note\n" +
+					"  Add a command\n" +
+					"  to foo:\n" +
+					"\n" +
+					"\n" +
+					"  ~~~\n" +
+					"  cilium foo\n" +
+					"    --bar\n" +
+					"  ~~~\n" +
+					"```\n",
+			},
+			want: "Add a command to foo:\n" +
+				"\n" +
+				"~~~\n" +
+				"cilium foo\n" +
+				"  --bar\n" +
+				"~~~",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -213,3 +252,67 @@ func Test_getBackportPRs(t *testing.T) {
 		})
 	}
 }
+
+func Test_getUpgradeNotes(t *testing.T) {
+	type args struct {
+		title string
+		body  string
+		lbls  []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "no upgrade notes",
+			args: args{
+				title: "Fix a crash",
+				body:  "```release-note\r\nFix a crash\r\n```",
+			},
+			want: "",
+		},
+		{
+			name: "multi-line block",
+			args: args{
+				title: "Remove --foo",
+				body: "Removes the flag.\r\n\r\n" +
+					"```release-note\r\nRemove the deprecated --foo flag\r\n```\r\n\r\n" +
+					"```upgrade-notes\r\n" +
+					"  The `--foo` flag was removed, use `--bar` instead:\r\n" +
+					"\r\n" +
+					"    * set `bar: true` in the Helm values  \r\n" +
+					"  * restart the agents\r\n" +
+					"```\r\n",
+			},
+			want: "The `--foo` flag was removed, use `--bar` instead:\n" +
+				"\n" +
+				"  * set `bar: true` in the Helm values\n" +
+				"* restart the agents",
+		},
+		{
+			name: "commented out block with label",
+			args: args{
+				title: "Remove --foo",
+				body:  "```release-note\nRemove the deprecated --foo flag\n```\n```upgrade-notes\n<!-- Describe the upgrade impact -->\n```",
+				lbls:  []string{"release-note/major", "upgrade-impact"},
+			},
+			want: "Remove the deprecated --foo flag",
+		},
+		{
+			name: "commented out block without label",
+			args: args{
+				title: "Remove --foo",
+				body:  "```upgrade-notes\n<!-- Describe the upgrade impact -->\n```",
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getUpgradeNotes(tt.args.title, tt.args.body, tt.args.lbls); got != tt.want {
+				t.Errorf("getUpgradeNotes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shurcooL/githubv4"
+
+	"github.com/cilium/release/pkg/types"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+
+	// DefaultMaxReleaseNoteLength is the default length, in characters, above
+	// which a release note is reported by the linter.
+	DefaultMaxReleaseNoteLength = 200
+)
+
+// LintOptions configures the checks done by LintPullRequests.
+type LintOptions struct {
+	MaxReleaseNoteLength int
+}
+
+// LintIssue is a problem found in the release note metadata of a PR.
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// LintResult lists the issues found in a PR.
+type LintResult struct {
+	PRNumber int         `json:"prNumber"`
+	Title    string      `json:"title"`
+	Author   string      `json:"author"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// HasErrors returns true if any of the issues is an error.
+func (r LintResult) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintReleaseNote returns the issues with the release note and release-note
+// labels of a PR, including the cases where getReleaseNote and
+// getReleaseLabel silently fall back to a default.
+func LintReleaseNote(title, body string, lbls []string, opts LintOptions) []LintIssue {
+	var issues []LintIssue
+
+	var releaseLabels []string
+	for _, lbl := range lbls {
+		if strings.HasPrefix(lbl, "release-note/") && lbl != EmbargoedLabel {
+			releaseLabels = append(releaseLabels, lbl)
+		}
+	}
+	switch {
+	case len(releaseLabels) == 0:
+		issues = append(issues, LintIssue{LintError, "missing-release-label",
+			"no release-note/* label, release-note/none is assumed"})
+	case len(releaseLabels) > 1:
+		issues = append(issues, LintIssue{LintError, "conflicting-release-labels",
+			fmt.Sprintf("multiple release-note/* labels (%s), %s is used", strings.Join(releaseLabels, ", "), releaseLabels[0])})
+	}
+
+	// PRs without user-facing changes don't need a release note.
+	fallbackSeverity := LintError
+	if getReleaseLabel(lbls) == "release-note/none" {
+		fallbackSeverity = LintWarning
+	}
+	if !strings.Contains(body, releaseNoteBlock) {
+		issues = append(issues, LintIssue{fallbackSeverity, "missing-release-note",
+			"no " + releaseNoteBlock + " block, the PR title is used instead"})
+	} else if block := markdownBlockBetween(body, releaseNoteBlock); len(block) == 0 || strings.Contains(block, commentTag) {
+		issues = append(issues, LintIssue{fallbackSeverity, "commented-release-note",
+			"the " + releaseNoteBlock + " block is empty or commented out, the PR title is used instead"})
+	}
+
+	if slices.Contains(lbls, upgradeImpactLabel) && getUpgradeNotes(title, body, nil) == "" {
+		issues = append(issues, LintIssue{LintWarning, "missing-upgrade-notes",
+			"the " + upgradeImpactLabel + " label is set but there is no " + upgradeNotesBlock + " block, the release note is used as upgrade notes"})
+	}
+
+	note := getReleaseNote(title, body)
+	if opts.MaxReleaseNoteLength > 0 && utf8.RuneCountInString(note) > opts.MaxReleaseNoteLength {
+		issues = append(issues, LintIssue{LintWarning, "release-note-too-long",
+			fmt.Sprintf("release note is %d characters long, more than %d", utf8.RuneCountInString(note), opts.MaxReleaseNoteLength)})
+	}
+	if last, _ := utf8.DecodeLastRuneInString(note); strings.ContainsRune(".,;:!", last) {
+		issues = append(issues, LintIssue{LintWarning, "trailing-punctuation",
+			fmt.Sprintf("release note ends with %q", last)})
+	}
+	if first, _ := utf8.DecodeRuneInString(note); unicode.IsLower(first) {
+		issues = append(issues, LintIssue{LintWarning, "lowercase-release-note",
+			"release note starts with a lowercase letter"})
+	}
+	return issues
+}
+
+// LintPullRequests fetches the given PRs and returns the issues found in
+// their release notes. The upstream-prs block of the given backport PRs is
+// checked for references to nonexistent PRs instead. Only PRs with issues
+// are returned, sorted by PR number.
+func LintPullRequests(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, prNumbers, backportPRNumbers []int, opts LintOptions) ([]LintResult, error) {
+	var results []LintResult
+
+	prs, err := fetchPullRequests(ctx, ghGQLClient, owner, repo, prNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for _, prNumber := range prNumbers {
+		pr, ok := prs[prNumber]
+		if !ok {
+			results = append(results, LintResult{
+				PRNumber: prNumber,
+				Issues:   []LintIssue{{LintError, "unknown-pr", "PR not found"}},
+			})
+			continue
+		}
+		issues := LintReleaseNote(string(pr.Title), string(pr.Body), pr.labels(), opts)
+		if len(issues) != 0 {
+			results = append(results, newLintResult(pr, issues))
+		}
+	}
+
+	backportPRs, err := fetchPullRequests(ctx, ghGQLClient, owner, repo, backportPRNumbers)
+	if err != nil {
+		return nil, err
+	}
+	var upstreamPRNumbers []int
+	for _, pr := range backportPRs {
+		upstreamPRNumbers = append(upstreamPRNumbers, getUpstreamPRs(string(pr.Body))...)
+	}
+	upstreamPRs, err := fetchPullRequests(ctx, ghGQLClient, owner, repo, upstreamPRNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for _, prNumber := range backportPRNumbers {
+		pr, ok := backportPRs[prNumber]
+		if !ok {
+			results = append(results, LintResult{
+				PRNumber: prNumber,
+				Issues:   []LintIssue{{LintError, "unknown-pr", "PR not found"}},
+			})
+			continue
+		}
+		var issues []LintIssue
+		for _, upstreamPRNumber := range getUpstreamPRs(string(pr.Body)) {
+			if _, ok := upstreamPRs[upstreamPRNumber]; !ok {
+				issues = append(issues, LintIssue{LintError, "unknown-upstream-pr",
+					fmt.Sprintf("the %s block references #%d which is not a PR", upstreamPRsBlock, upstreamPRNumber)})
+			}
+		}
+		if len(issues) != 0 {
+			results = append(results, newLintResult(pr, issues))
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].PRNumber < results[j].PRNumber
+	})
+	return results, nil
+}
+
+func newLintResult(pr gqlPullRequest, issues []LintIssue) LintResult {
+	return LintResult{
+		PRNumber: int(pr.Number),
+		Title:    string(pr.Title),
+		Author:   string(pr.Author.Login),
+		Issues:   issues,
+	}
+}
+
+// fetchPullRequests returns the given PRs, indexed by PR number. PRs that
+// don't exist are left out.
+func fetchPullRequests(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, prNumbers []int) (map[int]gqlPullRequest, error) {
+	found := map[int]gqlPullRequest{}
+	for start := 0; start < len(prNumbers); start += graphQLBatchSize {
+		batch := prNumbers[start:min(start+graphQLBatchSize, len(prNumbers))]
+		prs, err := batchQuery[gqlPullRequest](ctx, ghGQLClient, owner, repo, batch, func(i int, prNumber int) string {
+			return fmt.Sprintf("p%d: pullRequest(number: %d)", i, prNumber)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, pr := range prs {
+			if pr != nil {
+				found[batch[i]] = *pr
+			}
+		}
+	}
+	return found, nil
+}
+
+// PullRequestPreview is a PR as parsed when generating the release notes,
+// along with the issues found in its release note metadata.
+type PullRequestPreview struct {
+	Number      int
+	Title       string
+	State       string
+	PullRequest types.PullRequest
+	Issues      []LintIssue
+	// Backport is true if the PR has an upstream-prs block. Upstream then
+	// contains the upstream PRs, whose release notes are used instead of
+	// the one of the backport PR.
+	Backport bool
+	Upstream []PullRequestPreview
+}
+
+// PreviewPullRequests fetches the given PRs, which may still be open, and
+// parses them the same way GeneratePatchRelease does.
+func PreviewPullRequests(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, prNumbers []int, opts LintOptions) ([]PullRequestPreview, error) {
+	prs, err := fetchPullRequests(ctx, ghGQLClient, owner, repo, prNumbers)
+	if err != nil {
+		return nil, err
+	}
+	var upstreamPRNumbers []int
+	for _, prNumber := range prNumbers {
+		pr, ok := prs[prNumber]
+		if !ok {
+			return nil, fmt.Errorf("PR %d not found", prNumber)
+		}
+		upstreamPRNumbers = append(upstreamPRNumbers, getUpstreamPRs(string(pr.Body))...)
+	}
+	upstreamPRs, err := fetchPullRequests(ctx, ghGQLClient, owner, repo, upstreamPRNumbers)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]PullRequestPreview, 0, len(prNumbers))
+	for _, prNumber := range prNumbers {
+		pr := prs[prNumber]
+		preview := newPullRequestPreview(pr, opts)
+		upstreamPRNumbers := getUpstreamPRs(string(pr.Body))
+		if upstreamPRNumbers == nil {
+			previews = append(previews, preview)
+			continue
+		}
+		// Like in GeneratePatchRelease, the release note of a backport PR is
+		// never used.
+		preview.Backport = true
+		preview.Issues = nil
+		for _, upstreamPRNumber := range upstreamPRNumbers {
+			upstreamPR, ok := upstreamPRs[upstreamPRNumber]
+			if !ok {
+				preview.Issues = append(preview.Issues, LintIssue{LintError, "unknown-upstream-pr",
+					fmt.Sprintf("the %s block references #%d which is not a PR, it is left out of the release notes", upstreamPRsBlock, upstreamPRNumber)})
+				continue
+			}
+			upstream := newPullRequestPreview(upstreamPR, opts)
+			upstream.PullRequest.BackportBranches = nil
+			preview.Upstream = append(preview.Upstream, upstream)
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+func newPullRequestPreview(pr gqlPullRequest, opts LintOptions) PullRequestPreview {
+	lbls := pr.labels()
+	return PullRequestPreview{
+		Number: int(pr.Number),
+		Title:  string(pr.Title),
+		State:  string(pr.State),
+		PullRequest: types.PullRequest{
+			ReleaseNote:      getReleaseNote(string(pr.Title), string(pr.Body)),
+			Title:            string(pr.Title),
+			ReleaseLabel:     getReleaseLabel(lbls),
+			AuthorName:       string(pr.Author.Login),
+			BackportBranches: getBackportBranches(lbls),
+			Labels:           lbls,
+			UpgradeNotes:     getUpgradeNotes(string(pr.Title), string(pr.Body), lbls),
+		},
+		Issues: LintReleaseNote(string(pr.Title), string(pr.Body), lbls, opts),
+	}
+}
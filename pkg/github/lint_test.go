@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintReleaseNote(t *testing.T) {
+	opts := LintOptions{MaxReleaseNoteLength: 20}
+	rules := func(issues []LintIssue) []string {
+		var rules []string
+		for _, issue := range issues {
+			rules = append(rules, issue.Severity+"/"+issue.Rule)
+		}
+		return rules
+	}
+
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		lbls  []string
+		want  []string
+	}{
+		{
+			name: "valid",
+			body: "```release-note\nFix a crash\n```",
+			lbls: []string{"release-note/bug"},
+		},
+		{
+			name:  "missing release note and label",
+			title: "Fix a crash",
+			want:  []string{"error/missing-release-label", "warning/missing-release-note"},
+		},
+		{
+			name:  "commented out release note",
+			title: "Fix a crash",
+			body:  "```release-note\n<!-- Enter the release note text here if needed -->\n```",
+			lbls:  []string{"release-note/bug"},
+			want:  []string{"error/commented-release-note"},
+		},
+		{
+			// The release note is taken from the first block, like
+			// getReleaseNote does.
+			name: "template repeated after the release note",
+			body: "```release-note\nFix a crash\n```\n\n```release-note\n<!-- Enter the release note text here if needed -->\n```",
+			lbls: []string{"release-note/bug"},
+		},
+		{
+			name: "conflicting labels",
+			body: "```release-note\nFix a crash\n```",
+			lbls: []string{"release-note/bug", "release-note/minor"},
+			want: []string{"error/conflicting-release-labels"},
+		},
+		{
+			name: "embargoed",
+			body: "```release-note\nFix a crash\n```",
+			lbls: []string{"release-note/embargoed", "release-note/bug"},
+		},
+		{
+			name: "style",
+			body: "```release-note\nfix a crash in the agent.\n```",
+			lbls: []string{"release-note/bug"},
+			want: []string{"warning/release-note-too-long", "warning/trailing-punctuation", "warning/lowercase-release-note"},
+		},
+		{
+			name: "upgrade impact without upgrade notes",
+			body: "```release-note\nFix a crash\n```",
+			lbls: []string{"release-note/bug", "upgrade-impact"},
+			want: []string{"warning/missing-upgrade-notes"},
+		},
+		{
+			name: "upgrade impact with upgrade notes",
+			body: "```release-note\nFix a crash\n```\n```upgrade-notes\nRestart the agents.\n```",
+			lbls: []string{"release-note/bug", "upgrade-impact"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rules(LintReleaseNote(tt.title, tt.body, tt.lbls, opts)))
+		})
+	}
+}
+
+func TestLintPullRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ Query string }
+		json.Unmarshal(body, &req)
+		switch {
+		case strings.Contains(req.Query, "p1: pullRequest(number: 3)"):
+			io.WriteString(w, `{"data": {"repository": {
+				"p0": {"number": 1, "title": "Fix a crash", "body": "", "author": {"login": "alice"}, "labels": {"nodes": []}},
+				"p1": null
+			}}, "errors": [{"message": "Could not resolve to a PullRequest with the number of 3."}]}`)
+		case strings.Contains(req.Query, "p0: pullRequest(number: 1)"):
+			io.WriteString(w, `{"data": {"repository": {
+				"p0": {"number": 1, "title": "Fix a crash", "body": "", "author": {"login": "alice"},
+					"labels": {"nodes": [{"name": "release-note/bug"}]}},
+				"p1": {"number": 2, "title": "Add a feature", "body": "`+"```release-note\\nAdd a feature\\n```"+`",
+					"author": {"login": "bob"}, "labels": {"nodes": [{"name": "release-note/minor"}]}}
+			}}}`)
+		case strings.Contains(req.Query, "p0: pullRequest(number: 10)"):
+			io.WriteString(w, `{"data": {"repository": {
+				"p0": {"number": 10, "title": "v1.16 backports", "body": "`+"```upstream-prs\\n1 3\\n```"+`",
+					"author": {"login": "backporter"}, "labels": {"nodes": []}}
+			}}}`)
+		default:
+			t.Errorf("unexpected query: %s", req.Query)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	results, err := LintPullRequests(context.Background(), githubv4.NewEnterpriseClient(srv.URL, srv.Client()),
+		"cilium", "cilium", []int{1, 2}, []int{10}, LintOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []LintResult{
+		{
+			PRNumber: 1,
+			Title:    "Fix a crash",
+			Author:   "alice",
+			Issues: []LintIssue{{
+				Severity: LintError,
+				Rule:     "missing-release-note",
+				Message:  "no ```release-note block, the PR title is used instead",
+			}},
+		},
+		{
+			PRNumber: 10,
+			Title:    "v1.16 backports",
+			Author:   "backporter",
+			Issues: []LintIssue{{
+				Severity: LintError,
+				Rule:     "unknown-upstream-pr",
+				Message:  "the ```upstream-prs block references #3 which is not a PR",
+			}},
+		},
+	}, results)
+	assert.True(t, results[0].HasErrors())
+}
@@ -0,0 +1,313 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v62/github"
+	"github.com/schollz/progressbar/v3"
+)
+
+const (
+	// maxRateLimitRetries is the number of times a request is retried after
+	// hitting a rate limit.
+	maxRateLimitRetries = 5
+	// defaultSecondaryRateLimitWait is how long to wait after hitting a
+	// secondary rate limit that did not specify a Retry-After header.
+	defaultSecondaryRateLimitWait = time.Minute
+	// resetBuffer is added to the reset time of the primary rate limit to
+	// account for clock skew with GitHub.
+	resetBuffer = time.Second
+	// restAttemptTimeout and graphQLAttemptTimeout bound each attempt of a
+	// request, not counting the waits for the rate limits to reset.
+	restAttemptTimeout    = 45 * time.Second
+	graphQLAttemptTimeout = 2 * time.Minute
+)
+
+// quota is the last known state of a GitHub rate limit resource.
+type quota struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// rateLimits tracks the quota of all requests done by any client returned by
+// NewClient and NewGQLClient.
+var rateLimits = struct {
+	sync.Mutex
+	requests  int
+	retries   int
+	resources map[string]quota
+}{
+	resources: map[string]quota{},
+}
+
+func trackRateLimit(resp *http.Response) {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	rateLimits.requests++
+
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if resource == "" || err != nil {
+		return
+	}
+	q := quota{limit: limit}
+	q.remaining, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	q.reset = resetTime(resp)
+	rateLimits.resources[resource] = q
+}
+
+// PrintRateLimits writes the remaining quota of every GitHub API resource
+// used by this process into w. Nothing is written if no request was made.
+func PrintRateLimits(w io.Writer) {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	if rateLimits.requests == 0 {
+		return
+	}
+
+	resources := make([]string, 0, len(rateLimits.resources))
+	for resource := range rateLimits.resources {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	fmt.Fprintf(w, "GitHub API: %d requests, %d retried due to rate limits\n", rateLimits.requests, rateLimits.retries)
+	for _, resource := range resources {
+		q := rateLimits.resources[resource]
+		fmt.Fprintf(w, "  %s: %d/%d remaining, resets at %s\n", resource, q.remaining, q.limit, q.reset.Format(time.TimeOnly))
+	}
+}
+
+// rateLimitTransport waits for GitHub's primary and secondary rate limits to
+// reset and retries the idempotent requests that hit them. The deadline of the
+// request's context is ignored, since a reset may be an hour away: each
+// attempt is bounded by its own timeout instead. Canceling the context still
+// stops the request.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retriable := isIdempotent(req)
+	timeout := restAttemptTimeout
+	if isGraphQL(req) {
+		timeout = graphQLAttemptTimeout
+	}
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := withoutDeadline(req.Context())
+		ctx, cancelAttempt := context.WithTimeout(ctx, timeout)
+		cancelAll := func() { cancelAttempt(); cancel() }
+		r := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancelAll()
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			cancelAll()
+			return nil, err
+		}
+		// The attempt lasts until its response body is closed.
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancelAll}
+		trackRateLimit(resp)
+
+		wait, reason, limited := rateLimitWait(resp, attempt)
+		if !limited {
+			// If this request used the last bit of the quota, wait for the
+			// reset before returning. Otherwise the next request would fail,
+			// or not even be made by go-github, with a RateLimitError.
+			if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				if reset := resetTime(resp); !reset.IsZero() {
+					err = waitFor(req.Context(), time.Until(reset)+resetBuffer,
+						fmt.Sprintf("GitHub %s rate limit exhausted", resp.Header.Get("X-RateLimit-Resource")))
+					if err != nil {
+						resp.Body.Close()
+						return nil, err
+					}
+				}
+			}
+			return resp, nil
+		}
+		if !retriable || attempt >= maxRateLimitRetries {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := waitFor(req.Context(), wait, reason); err != nil {
+			return nil, err
+		}
+		rateLimits.Lock()
+		rateLimits.retries++
+		rateLimits.Unlock()
+	}
+}
+
+// cancelOnClose cancels the context of a request once its response body is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// withoutDeadline returns a context that is canceled along with ctx, unless
+// ctx is done because its deadline expired.
+func withoutDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	noDeadlineCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+	return noDeadlineCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying the request that
+// returned resp, and whether resp was rejected due to a rate limit.
+func rateLimitWait(resp *http.Response, attempt int) (time.Duration, string, bool) {
+	if resp.StatusCode == http.StatusOK && isGraphQL(resp.Request) {
+		// The GraphQL API reports primary rate limits as errors with a
+		// successful status code.
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil || !bytes.Contains(data, []byte(`"RATE_LIMITED"`)) {
+			return 0, "", false
+		}
+		if reset := resetTime(resp); !reset.IsZero() {
+			return time.Until(reset) + resetBuffer, "GitHub GraphQL rate limit exceeded", true
+		}
+		return defaultSecondaryRateLimitWait << attempt, "GitHub GraphQL rate limit exceeded", true
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, "", false
+	}
+
+	// CheckResponse only recognizes rate limits on 403 responses, but GitHub
+	// may also return them with 429.
+	statusCode := resp.StatusCode
+	resp.StatusCode = http.StatusForbidden
+	err := gh.CheckResponse(resp)
+	resp.StatusCode = statusCode
+
+	var (
+		rateLimitErr      *gh.RateLimitError
+		abuseRateLimitErr *gh.AbuseRateLimitError
+	)
+	switch {
+	case errors.As(err, &rateLimitErr):
+		return time.Until(rateLimitErr.Rate.Reset.Time) + resetBuffer, "GitHub rate limit exceeded", true
+	case errors.As(err, &abuseRateLimitErr):
+		if abuseRateLimitErr.RetryAfter != nil {
+			return *abuseRateLimitErr.RetryAfter, "GitHub secondary rate limit exceeded", true
+		}
+		return defaultSecondaryRateLimitWait << attempt, "GitHub secondary rate limit exceeded", true
+	case statusCode == http.StatusTooManyRequests:
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(retryAfter) * time.Second, "GitHub secondary rate limit exceeded", true
+		}
+		return defaultSecondaryRateLimitWait << attempt, "GitHub secondary rate limit exceeded", true
+	}
+	return 0, "", false
+}
+
+func resetTime(resp *http.Response) time.Time {
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(reset, 0)
+}
+
+func isGraphQL(req *http.Request) bool {
+	return req != nil && strings.HasSuffix(req.URL.Path, "/graphql")
+}
+
+// isIdempotent returns true if req can be safely retried. GraphQL requests are
+// always POSTs, so only queries are considered to be idempotent.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		if !isGraphQL(req) || req.GetBody == nil {
+			return false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return false
+		}
+		defer body.Close()
+		var in struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(body).Decode(&in); err != nil {
+			return false
+		}
+		return !strings.HasPrefix(strings.TrimSpace(in.Query), "mutation")
+	}
+	return false
+}
+
+// waitFor blocks for d while displaying a countdown, or until ctx is
+// canceled. The deadline of ctx is ignored.
+func waitFor(ctx context.Context, d time.Duration, reason string) error {
+	if d <= 0 {
+		return nil
+	}
+	ctx, cancel := withoutDeadline(ctx)
+	defer cancel()
+	seconds := int64(d.Round(time.Second) / time.Second)
+	bar := progressbar.NewOptions64(seconds,
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionSetDescription(fmt.Sprintf("%s, waiting until %s", reason, time.Now().Add(d).Format(time.TimeOnly))),
+		progressbar.OptionShowCount(),
+		progressbar.OptionOnCompletion(func() { fmt.Fprintln(os.Stderr) }),
+	)
+	defer bar.Finish()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			bar.Add(1)
+		}
+	}
+}
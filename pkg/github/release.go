@@ -19,7 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
+	"slices"
 
 	gh "github.com/google/go-github/v62/github"
 	"github.com/schollz/progressbar/v3"
@@ -30,6 +30,8 @@ import (
 // GeneratePatchRelease will returns a map that maps the backport PR number to
 // the upstream PR number and a map that maps the backport PR number to the PR
 // if no upstream PR was found.
+// The messages of the commits, indexed by SHA, are fetched unless they are
+// found in messages.
 // In case of an error, a list of non-processed commits will be returned.
 func GeneratePatchRelease(
 	ctx context.Context,
@@ -42,6 +44,7 @@ func GeneratePatchRelease(
 	listOfPRs types.PullRequests,
 	nodeIDs types.NodeIDs,
 	commits []string,
+	messages map[string]string,
 ) (
 	types.BackportPRs,
 	types.PullRequests,
@@ -50,16 +53,53 @@ func GeneratePatchRelease(
 	error,
 ) {
 
+	links := newUpstreamLinks()
+	commitPRs := map[string]int{}
+	// addUpstreamPRs fetches the given upstream PRs of the backport PR pr
+	// and adds them to backportPRs.
+	addUpstreamPRs := func(pr *gh.PullRequest, upstreamPRs []int) error {
+		for _, upstreamPRNumber := range upstreamPRs {
+			_, ok := backportPRs[pr.GetNumber()][upstreamPRNumber]
+			if ok {
+				continue
+			}
+			upstreamPR, _, err := ghClient.PullRequests.Get(ctx, owner, repo, upstreamPRNumber)
+			if err != nil {
+				var ghErrRespon *gh.ErrorResponse
+				if errors.As(err, &ghErrRespon) && ghErrRespon.Response.StatusCode == http.StatusNotFound {
+					printer(fmt.Sprintf("\nWARNING: PR not found %d!\n", upstreamPRNumber))
+					continue
+				}
+				return err
+			}
+			lbls := parseGHLabels(upstreamPR.Labels)
+			backportPRs[pr.GetNumber()][upstreamPRNumber] = types.PullRequest{
+				ReleaseNote:  getReleaseNote(upstreamPR.GetTitle(), upstreamPR.GetBody()),
+				Title:        upstreamPR.GetTitle(),
+				ReleaseLabel: getReleaseLabel(lbls),
+				AuthorName:   upstreamPR.GetUser().GetLogin(),
+				Labels:       lbls,
+				Reverts:      getRevertedPRs(owner, repo, upstreamPR.GetTitle(), upstreamPR.GetBody()),
+				UpgradeNotes: getUpgradeNotes(upstreamPR.GetTitle(), upstreamPR.GetBody(), lbls),
+			}
+			nodeIDs[pr.GetNumber()] = pr.GetNodeID()
+			nodeIDs[upstreamPR.GetNumber()] = upstreamPR.GetNodeID()
+		}
+		return nil
+	}
+
 	for i, sha := range commits {
 		bar.Add(1)
+		trailerPRs, revertedPRs, err := restCommitPRs(ctx, ghClient, owner, repo, printer, sha, messages, commitPRs)
+		if err != nil {
+			return backportPRs, listOfPRs, nodeIDs, commits[i:], err
+		}
 		page := 0
 		foundPR := false
 		for {
-			ctxWithTimeout, cancel := context.WithTimeout(ctx, 45*time.Second)
-			prs, resp, err := ghClient.PullRequests.ListPullRequestsWithCommit(ctxWithTimeout, owner, repo, sha, &gh.ListOptions{
+			prs, resp, err := ghClient.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, &gh.ListOptions{
 				Page: page,
 			})
-			cancel()
 			if err != nil {
 				return backportPRs, listOfPRs, nodeIDs, commits[i:], err
 			}
@@ -67,6 +107,12 @@ func GeneratePatchRelease(
 			for _, pr := range prs {
 				_, ok := listOfPRs[pr.GetNumber()]
 				_, ok2 := backportPRs[pr.GetNumber()]
+				if ok2 && len(trailerPRs) != 0 {
+					links.addTrailers(pr.GetNumber(), trailerPRs)
+					if err := addUpstreamPRs(pr, trailerPRs); err != nil {
+						return backportPRs, listOfPRs, nodeIDs, commits[i:], err
+					}
+				}
 				if ok || ok2 {
 					foundPR = true
 					continue
@@ -76,45 +122,30 @@ func GeneratePatchRelease(
 				}
 				foundPR = true
 				upstreamPRs := getUpstreamPRs(pr.GetBody())
-				if upstreamPRs == nil {
+				// Backports whose body was edited may have lost their
+				// upstream-prs block but their commits still reference the
+				// upstream commits.
+				if upstreamPRs == nil && len(trailerPRs) == 0 {
 					lbls := parseGHLabels(pr.Labels)
 					listOfPRs[pr.GetNumber()] = types.PullRequest{
 						ReleaseNote:      getReleaseNote(pr.GetTitle(), pr.GetBody()),
+						Title:            pr.GetTitle(),
 						ReleaseLabel:     getReleaseLabel(lbls),
 						AuthorName:       pr.GetUser().GetLogin(),
 						BackportBranches: getBackportBranches(lbls),
 						Labels:           lbls,
+						Reverts:          mergePRNumbers(getRevertedPRs(owner, repo, pr.GetTitle(), pr.GetBody()), revertedPRs),
+						UpgradeNotes:     getUpgradeNotes(pr.GetTitle(), pr.GetBody(), lbls),
 					}
 					nodeIDs[pr.GetNumber()] = pr.GetNodeID()
 					continue
 				}
+				links.addBody(pr.GetNumber(), upstreamPRs)
+				links.addTrailers(pr.GetNumber(), trailerPRs)
 				backportPRs[pr.GetNumber()] = map[int]types.PullRequest{}
-				for _, upstreamPRNumber := range upstreamPRs {
-					_, ok := backportPRs[pr.GetNumber()][upstreamPRNumber]
-					if ok {
-						continue
-					}
-					ctxWithTimeout, cancel := context.WithTimeout(ctx, 45*time.Second)
-					upstreamPR, _, err := ghClient.PullRequests.Get(ctxWithTimeout, owner, repo, upstreamPRNumber)
-					cancel()
-					if err != nil {
-						var ghErrRespon *gh.ErrorResponse
-						if errors.As(err, &ghErrRespon) && ghErrRespon.Response.StatusCode == http.StatusNotFound {
-							printer(fmt.Sprintf("\nWARNING: PR not found %d!\n", upstreamPRNumber))
-							continue
-						}
-						delete(backportPRs, pr.GetNumber())
-						return backportPRs, listOfPRs, nodeIDs, commits[i:], err
-					}
-					lbls := parseGHLabels(upstreamPR.Labels)
-					backportPRs[pr.GetNumber()][upstreamPRNumber] = types.PullRequest{
-						ReleaseNote:  getReleaseNote(upstreamPR.GetTitle(), upstreamPR.GetBody()),
-						ReleaseLabel: getReleaseLabel(lbls),
-						AuthorName:   upstreamPR.GetUser().GetLogin(),
-						Labels:       lbls,
-					}
-					nodeIDs[pr.GetNumber()] = pr.GetNodeID()
-					nodeIDs[upstreamPR.GetNumber()] = upstreamPR.GetNodeID()
+				if err := addUpstreamPRs(pr, links.upstreamPRs(pr.GetNumber())); err != nil {
+					delete(backportPRs, pr.GetNumber())
+					return backportPRs, listOfPRs, nodeIDs, commits[i:], err
 				}
 			}
 
@@ -127,5 +158,87 @@ func GeneratePatchRelease(
 			printer(fmt.Sprintf("\nWARNING: PR not found for commit %s!\n", sha))
 		}
 	}
+	links.report(printer)
 	return backportPRs, listOfPRs, nodeIDs, nil, nil
 }
+
+// restCommitPRs returns the upstream PRs referenced by the upstream commit
+// trailers of the commit sha, and the PRs of the commits it reverts. Reverted
+// commits with a single upstream commit trailer are resolved to the upstream
+// PR, so that they match its entry rather than any entry of the backport PR.
+// The messages of the commits are only fetched if they are not in messages.
+// The commits already resolved are cached in commitPRs.
+func restCommitPRs(ctx context.Context, ghClient *gh.Client, owner, repo string, printer func(msg string), sha string, messages map[string]string, commitPRs map[string]int) ([]int, []int, error) {
+	message, err := restCommitMessage(ctx, ghClient, owner, repo, sha, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+	upstreamSHAs, revertedSHAs := getUpstreamCommits(message), getRevertedCommits(message)
+
+	for _, commitSHA := range slices.Concat(upstreamSHAs, revertedSHAs) {
+		if _, ok := commitPRs[commitSHA]; ok {
+			continue
+		}
+		isBackport, err := restResolveCommitPR(ctx, ghClient, owner, repo, commitSHA, revertedSHAs, commitPRs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := commitPRs[commitSHA]; !ok && slices.Contains(upstreamSHAs, commitSHA) {
+			printer(fmt.Sprintf("\nWARNING: PR not found for upstream commit %s!\n", commitSHA))
+		}
+		if !isBackport {
+			continue
+		}
+
+		revertedMessage, err := restCommitMessage(ctx, ghClient, owner, repo, commitSHA, messages)
+		if err != nil {
+			return nil, nil, err
+		}
+		trailerSHAs := getUpstreamCommits(revertedMessage)
+		if len(trailerSHAs) != 1 {
+			continue
+		}
+		if _, ok := commitPRs[trailerSHAs[0]]; !ok {
+			if _, err := restResolveCommitPR(ctx, ghClient, owner, repo, trailerSHAs[0], nil, commitPRs); err != nil {
+				return nil, nil, err
+			}
+		}
+		if prNumber, ok := commitPRs[trailerSHAs[0]]; ok {
+			commitPRs[commitSHA] = prNumber
+		}
+	}
+	return commitPRNumbers(upstreamSHAs, commitPRs), commitPRNumbers(revertedSHAs, commitPRs), nil
+}
+
+// restCommitMessage returns the message of the commit sha, from messages if
+// it is there.
+func restCommitMessage(ctx context.Context, ghClient *gh.Client, owner, repo, sha string, messages map[string]string) (string, error) {
+	if message, ok := messages[sha]; ok {
+		return message, nil
+	}
+	commit, _, err := ghClient.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return "", err
+	}
+	return commit.GetCommit().GetMessage(), nil
+}
+
+// restResolveCommitPR stores in commitPRs the PR that merged the commit sha,
+// see isCommitPR, if any. It returns true if that PR is a backport PR.
+func restResolveCommitPR(ctx context.Context, ghClient *gh.Client, owner, repo, sha string, revertedSHAs []string, commitPRs map[string]int) (bool, error) {
+	prs, _, err := ghClient.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		var ghErrRespon *gh.ErrorResponse
+		if !errors.As(err, &ghErrRespon) || ghErrRespon.Response.StatusCode != http.StatusUnprocessableEntity {
+			return false, err
+		}
+	}
+	for _, pr := range prs {
+		isBackport := getUpstreamPRs(pr.GetBody()) != nil
+		if pr.MergedAt != nil && isCommitPR(sha, isBackport, revertedSHAs) {
+			commitPRs[sha] = pr.GetNumber()
+			return isBackport, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"bufio"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// revertCommitRegex matches the line added by git revert to the
+	// message of the commits it creates.
+	revertCommitRegex = regexp.MustCompile(`^This reverts commit ([0-9a-f]{7,40})\b`)
+	// revertPRRegex matches the "Reverts owner/repo#N" body of the PRs
+	// created with the GitHub revert button, as well as "Revert #N" titles.
+	revertPRRegex = regexp.MustCompile(`(?i)^reverts?:?\s+([\w.-]+/[\w.-]+)?#(\d+)\b`)
+)
+
+// getRevertedCommits returns the SHAs of the commits reverted by the given
+// commit message.
+func getRevertedCommits(message string) []string {
+	var shas []string
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	for scanner.Scan() {
+		m := revertCommitRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m != nil && !slices.Contains(shas, m[1]) {
+			shas = append(shas, m[1])
+		}
+	}
+	return shas
+}
+
+// getRevertedPRs returns the PRs of owner/repo reverted by a PR, as stated by
+// its title or the lines of its body.
+func getRevertedPRs(owner, repo, title, body string) []int {
+	var prNumbers []int
+	lines := []string{title}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	for _, line := range lines {
+		m := revertPRRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		if len(m[1]) != 0 && !strings.EqualFold(m[1], owner+"/"+repo) {
+			continue
+		}
+		prNumber, err := strconv.Atoi(m[2])
+		if err == nil && !slices.Contains(prNumbers, prNumber) {
+			prNumbers = append(prNumbers, prNumber)
+		}
+	}
+	return prNumbers
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getRevertedCommits(t *testing.T) {
+	assert.Nil(t, getRevertedCommits("bpf: Fix a crash\n\nSigned-off-by: Alice <alice@example.com>"))
+	assert.Equal(t, []string{"0123456789abcdef0123456789abcdef01234567"}, getRevertedCommits(
+		"Revert \"bpf: Fix a crash\"\r\n\r\nThis reverts commit 0123456789abcdef0123456789abcdef01234567.\r\n\r\nSigned-off-by: Bob <bob@example.com>",
+	))
+	assert.Equal(t, []string{"aaaaaaa", "bbbbbbb"}, getRevertedCommits(
+		"Revert two commits\n\nThis reverts commit aaaaaaa.\nThis reverts commit bbbbbbb, reversing\nchanges made to ccccccc.",
+	))
+}
+
+func Test_getRevertedPRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		want  []int
+	}{
+		{
+			name:  "not a revert",
+			title: "Revert the default of --enable-foo",
+			body:  "This PR reverts the default, see #123.",
+			want:  nil,
+		},
+		{
+			name:  "GitHub revert button",
+			title: `Revert "bpf: Fix a crash"`,
+			body:  "Reverts cilium/cilium#123\r\n\r\nIt breaks the CI.",
+			want:  []int{123},
+		},
+		{
+			name:  "title",
+			title: "Revert #123",
+			body:  "Reverts #123",
+			want:  []int{123},
+		},
+		{
+			name:  "other repository",
+			title: `Revert "Bump tetragon"`,
+			body:  "Reverts cilium/tetragon#123",
+			want:  nil,
+		},
+		{
+			name:  "several PRs",
+			title: "Revert the new loader",
+			body:  "revert: #123\nrevert: #124",
+			want:  []int{123, 124},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getRevertedPRs("cilium", "cilium", tt.title, tt.body))
+		})
+	}
+}
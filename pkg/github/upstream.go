@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// upstreamCommitRegex matches the trailer added by the backporting scripts to
+// each backported commit, e.g. "[ upstream commit 0123abcd ]".
+var upstreamCommitRegex = regexp.MustCompile(`^\[\s*upstream commit ([0-9a-f]{7,40})\s*\]$`)
+
+// getUpstreamCommits returns the SHAs of the upstream commits referenced by
+// the trailers of the given commit message. Squashed backports may reference
+// several upstream commits.
+func getUpstreamCommits(message string) []string {
+	var shas []string
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	for scanner.Scan() {
+		m := upstreamCommitRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m != nil && !slices.Contains(shas, m[1]) {
+			shas = append(shas, m[1])
+		}
+	}
+	return shas
+}
+
+// commitPRNumbers returns the PRs of the given commits, as resolved in
+// commitPRs. Commits that were not resolved are left out.
+func commitPRNumbers(shas []string, commitPRs map[string]int) []int {
+	var prNumbers []int
+	for _, sha := range shas {
+		if prNumber, ok := commitPRs[sha]; ok {
+			prNumbers = append(prNumbers, prNumber)
+		}
+	}
+	return prNumbers
+}
+
+// mergePRNumbers returns the PRs of a, followed by the ones of b that are
+// not in a.
+func mergePRNumbers(a, b []int) []int {
+	merged := slices.Clone(a)
+	for _, prNumber := range b {
+		if !slices.Contains(merged, prNumber) {
+			merged = append(merged, prNumber)
+		}
+	}
+	return merged
+}
+
+// upstreamLinks records the two sources of the upstream PRs of each backport
+// PR: the upstream-prs block of its body and the upstream commit trailers of
+// its commits.
+type upstreamLinks struct {
+	// body holds the upstream-prs block of the backport PRs, which is nil
+	// if the PR doesn't have one.
+	body     map[int][]int
+	trailers map[int][]int
+}
+
+func newUpstreamLinks() *upstreamLinks {
+	return &upstreamLinks{
+		body:     map[int][]int{},
+		trailers: map[int][]int{},
+	}
+}
+
+func (l *upstreamLinks) addBody(prNumber int, upstreamPRs []int) {
+	l.body[prNumber] = upstreamPRs
+}
+
+func (l *upstreamLinks) addTrailers(prNumber int, upstreamPRs []int) {
+	if len(upstreamPRs) != 0 {
+		l.trailers[prNumber] = mergePRNumbers(l.trailers[prNumber], upstreamPRs)
+	}
+}
+
+// upstreamPRs returns the upstream PRs of prNumber found in both sources.
+func (l *upstreamLinks) upstreamPRs(prNumber int) []int {
+	return mergePRNumbers(l.body[prNumber], l.trailers[prNumber])
+}
+
+// report prints the backport PRs for which the upstream-prs block and the
+// upstream commit trailers disagree. Backport PRs without trailers, or whose
+// body was not seen, are not reported.
+func (l *upstreamLinks) report(printer func(msg string)) {
+	var prNumbers []int
+	for prNumber := range l.trailers {
+		prNumbers = append(prNumbers, prNumber)
+	}
+	sort.Ints(prNumbers)
+
+	for _, prNumber := range prNumbers {
+		body, ok := l.body[prNumber]
+		if !ok {
+			continue
+		}
+		trailers := l.trailers[prNumber]
+		if body == nil {
+			printer(fmt.Sprintf("\nWARNING: Backport PR %d doesn't have an upstream-prs block, using upstream PRs %v from its upstream commit trailers\n", prNumber, trailers))
+			continue
+		}
+		for _, upstreamPRNumber := range trailers {
+			if !slices.Contains(body, upstreamPRNumber) {
+				printer(fmt.Sprintf("\nWARNING: Backport PR %d: upstream PR %d is referenced by an upstream commit trailer but missing from the upstream-prs block\n", prNumber, upstreamPRNumber))
+			}
+		}
+		for _, upstreamPRNumber := range body {
+			if !slices.Contains(trailers, upstreamPRNumber) {
+				printer(fmt.Sprintf("\nWARNING: Backport PR %d: upstream PR %d is listed in the upstream-prs block but no commit has an upstream commit trailer for it\n", prNumber, upstreamPRNumber))
+			}
+		}
+	}
+}
+
+// isCommitPR returns true if the merged PR, which is a backport PR if
+// isBackport is set, is the one to resolve the commit sha to. The upstream
+// commits of backports are resolved to their upstream PR, so backport PRs are
+// ignored, unless sha is one of the reverted commits: reverts on stable
+// branches revert the backported commits.
+func isCommitPR(sha string, isBackport bool, revertedSHAs []string) bool {
+	return !isBackport || slices.Contains(revertedSHAs, sha)
+}
+
+// resolveCommitPRs returns the PR that merged each of the given upstream and
+// reverted commits, indexed by SHA, see isCommitPR. Reverted commits with a
+// single upstream commit trailer are resolved to the upstream PR instead, so
+// that they match its entry rather than any entry of the backport PR. Commits
+// for which no merged PR is found are left out.
+func resolveCommitPRs(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, upstreamSHAs, revertedSHAs []string) (map[string]int, error) {
+	shas := slices.Compact(slices.Sorted(slices.Values(slices.Concat(upstreamSHAs, revertedSHAs))))
+	found, messages, err := queryCommitPRs(ctx, ghGQLClient, owner, repo, shas, revertedSHAs)
+	if err != nil {
+		return nil, err
+	}
+
+	revertedUpstreamSHAs := map[string]string{}
+	var trailerSHAs []string
+	for _, sha := range revertedSHAs {
+		upstreamSHAs := getUpstreamCommits(messages[sha])
+		if len(upstreamSHAs) != 1 {
+			continue
+		}
+		revertedUpstreamSHAs[sha] = upstreamSHAs[0]
+		if _, ok := found[upstreamSHAs[0]]; !ok {
+			trailerSHAs = append(trailerSHAs, upstreamSHAs[0])
+		}
+	}
+	trailerPRs, _, err := queryCommitPRs(ctx, ghGQLClient, owner, repo, slices.Compact(slices.Sorted(slices.Values(trailerSHAs))), nil)
+	if err != nil {
+		return nil, err
+	}
+	for sha, upstreamSHA := range revertedUpstreamSHAs {
+		if prNumber, ok := found[upstreamSHA]; ok {
+			found[sha] = prNumber
+		} else if prNumber, ok := trailerPRs[upstreamSHA]; ok {
+			found[sha] = prNumber
+		}
+	}
+	return found, nil
+}
+
+// queryCommitPRs returns the PR that merged each of the given commits, see
+// isCommitPR, and the messages of the commits, both indexed by SHA.
+func queryCommitPRs(ctx context.Context, ghGQLClient *githubv4.Client, owner, repo string, shas, revertedSHAs []string) (map[string]int, map[string]string, error) {
+	found := map[string]int{}
+	messages := map[string]string{}
+	for start := 0; start < len(shas); start += graphQLBatchSize {
+		batch := shas[start:min(start+graphQLBatchSize, len(shas))]
+		gqlCommits, err := batchQuery[gqlCommit](ctx, ghGQLClient, owner, repo, batch, func(i int, sha string) string {
+			return fmt.Sprintf("c%d: object(expression: %q)", i, sha)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, gqlCommit := range gqlCommits {
+			if gqlCommit == nil {
+				continue
+			}
+			messages[batch[i]] = string(gqlCommit.Commit.Message)
+			for _, pr := range gqlCommit.Commit.AssociatedPullRequests.Nodes {
+				if pr.State == githubv4.PullRequestStateMerged && isCommitPR(batch[i], getUpstreamPRs(string(pr.Body)) != nil, revertedSHAs) {
+					found[batch[i]] = int(pr.Number)
+					break
+				}
+			}
+		}
+	}
+	return found, messages, nil
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getUpstreamCommits(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{
+			name:    "no trailer",
+			message: "bpf: Fix a crash\n\nSigned-off-by: Alice <alice@example.com>",
+			want:    nil,
+		},
+		{
+			name:    "single trailer",
+			message: "bpf: Fix a crash\n\n[ upstream commit 0123456789abcdef0123456789abcdef01234567 ]\n\nSigned-off-by: Alice <alice@example.com>",
+			want:    []string{"0123456789abcdef0123456789abcdef01234567"},
+		},
+		{
+			name:    "squashed commits without spaces and with CRLF",
+			message: "Squashed backports\r\n\r\n[upstream commit aaaaaaa]\r\n  [ upstream commit bbbbbbb ]  \r\n[ upstream commit aaaaaaa ]",
+			want:    []string{"aaaaaaa", "bbbbbbb"},
+		},
+		{
+			name:    "trailer in the middle of a line",
+			message: "See [ upstream commit aaaaaaa ] for details",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getUpstreamCommits(tt.message))
+		})
+	}
+}
+
+func Test_upstreamLinks_report(t *testing.T) {
+	links := newUpstreamLinks()
+	links.addBody(10, []int{1, 2})
+	links.addTrailers(10, []int{1})
+	links.addTrailers(10, []int{3})
+	links.addBody(11, nil)
+	links.addTrailers(11, []int{4})
+	links.addBody(12, []int{5})
+	links.addTrailers(12, []int{5})
+	// Found in a previous run, its body is unknown.
+	links.addTrailers(13, []int{6})
+
+	assert.Equal(t, []int{1, 2, 3}, links.upstreamPRs(10))
+	assert.Equal(t, []int{4}, links.upstreamPRs(11))
+
+	var warnings []string
+	links.report(func(msg string) { warnings = append(warnings, msg) })
+	assert.Equal(t, []string{
+		"\nWARNING: Backport PR 10: upstream PR 3 is referenced by an upstream commit trailer but missing from the upstream-prs block\n",
+		"\nWARNING: Backport PR 10: upstream PR 2 is listed in the upstream-prs block but no commit has an upstream commit trailer for it\n",
+		"\nWARNING: Backport PR 11 doesn't have an upstream-prs block, using upstream PRs [4] from its upstream commit trailers\n",
+	}, warnings)
+}
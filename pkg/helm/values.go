@@ -0,0 +1,339 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cilium/release/pkg/git"
+)
+
+// DefaultValuesFile is the path, in the Cilium repository, of the default
+// values of the Helm chart.
+const DefaultValuesFile = "install/kubernetes/cilium/values.yaml"
+
+// DefaultIgnoredValues are the patterns of the values updated by every
+// release, which are left out of the diffs. The operator image has a digest
+// per cloud provider.
+var DefaultIgnoredValues = []string{
+	"image.tag",
+	"image.digest",
+	"image.genericDigest",
+	"image.awsDigest",
+	"image.azureDigest",
+	"image.alibabacloudDigest",
+}
+
+const (
+	ValueAdded   = "added"
+	ValueRemoved = "removed"
+	ValueRenamed = "renamed"
+	ValueChanged = "changed"
+)
+
+// ValueChange is a Helm value that was added, removed, renamed or whose
+// default changed between two versions of a values file. Keys are the dotted
+// paths of the values, e.g. "hubble.relay.enabled".
+type ValueChange struct {
+	Key string `json:"key" yaml:"key"`
+	// NewKey is the new key of renamed values.
+	NewKey   string `json:"newKey,omitempty" yaml:"newKey,omitempty"`
+	Change   string `json:"change" yaml:"change"`
+	OldValue any    `json:"oldValue,omitempty" yaml:"oldValue,omitempty"`
+	NewValue any    `json:"newValue,omitempty" yaml:"newValue,omitempty"`
+}
+
+// Markdown returns the change as a Markdown bullet.
+func (c ValueChange) Markdown() string {
+	switch c.Change {
+	case ValueAdded:
+		return fmt.Sprintf("* `%s` was added with default `%s`", c.Key, formatValue(c.NewValue))
+	case ValueRemoved:
+		return fmt.Sprintf("* `%s` was removed, its default was `%s`", c.Key, formatValue(c.OldValue))
+	case ValueRenamed:
+		return fmt.Sprintf("* `%s` was renamed to `%s`", c.Key, c.NewKey)
+	default:
+		return fmt.Sprintf("* `%s` default changed from `%s` to `%s`", c.Key, formatValue(c.OldValue), formatValue(c.NewValue))
+	}
+}
+
+func formatValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// ParseValues returns the leaf values of the values file data indexed by
+// their dotted key. Lists and empty maps are leaf values.
+func ParseValues(data string) (map[string]any, error) {
+	var root any
+	if err := yaml.Unmarshal([]byte(data), &root); err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	flattenValues("", root, values)
+	return values, nil
+}
+
+func flattenValues(prefix string, v any, values map[string]any) {
+	var m map[string]any
+	switch v := v.(type) {
+	case map[string]any:
+		m = v
+	case map[any]any:
+		m = make(map[string]any, len(v))
+		for k, child := range v {
+			m[fmt.Sprint(k)] = child
+		}
+	}
+	if len(m) == 0 {
+		if len(prefix) != 0 {
+			values[prefix] = v
+		}
+		return
+	}
+	for k, child := range m {
+		key := k
+		if len(prefix) != 0 {
+			key = prefix + "." + k
+		}
+		flattenValues(key, child, values)
+	}
+}
+
+// ignored returns true if key, or any of its suffixes made of whole
+// segments, matches one of the patterns. Patterns use the syntax of
+// path.Match with dots as separators, e.g. "image.*".
+func ignored(key string, patterns []string) bool {
+	segments := strings.Split(key, ".")
+	for i := range segments {
+		suffix := strings.Join(segments[i:], "/")
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ReplaceAll(pattern, ".", "/"), suffix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func lastSegment(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
+func parentKey(key string) string {
+	return key[:max(strings.LastIndex(key, "."), 0)]
+}
+
+// hasChildren returns true if values has keys under parent. The root, "", is
+// considered to have none so that it never prevents a rename.
+func hasChildren(values map[string]any, parent string) bool {
+	if len(parent) == 0 {
+		return false
+	}
+	for key := range values {
+		if strings.HasPrefix(key, parent+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// reparent returns key, which is under oldParent, moved under newParent.
+func reparent(key, oldParent, newParent string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, oldParent), ".")
+	if len(newParent) == 0 {
+		return key
+	}
+	return newParent + "." + key
+}
+
+// renames returns the removed keys that were renamed into added ones, indexed
+// by removed key. A removed key may be renamed into an added one if both have
+// the same last key segment and default, and if a sibling was moved to the
+// new parent as well or, less likely, if the old parent is gone and the new
+// parent is new. Only the most likely match of a key is kept, and only if it
+// is the most likely match of the other key too and no other match is as
+// likely, e.g. the "enabled" flags of two unrelated features are never
+// renamed into each other.
+func renames(old, new map[string]any, removed, added []string) map[string]string {
+	const (
+		unlikely = iota
+		parentMoved
+		siblingMoved
+	)
+	likelihood := func(oldKey, newKey string) int {
+		if lastSegment(oldKey) != lastSegment(newKey) || !reflect.DeepEqual(old[oldKey], new[newKey]) {
+			return unlikely
+		}
+		oldParent, newParent := parentKey(oldKey), parentKey(newKey)
+		for _, sibling := range removed {
+			if sibling == oldKey || parentKey(sibling) != oldParent {
+				continue
+			}
+			newSibling := reparent(sibling, oldParent, newParent)
+			if slices.Contains(added, newSibling) && reflect.DeepEqual(old[sibling], new[newSibling]) {
+				return siblingMoved
+			}
+		}
+		if !hasChildren(new, oldParent) && !hasChildren(old, newParent) {
+			return parentMoved
+		}
+		return unlikely
+	}
+
+	// best returns the most likely match of key among keys, if it is the
+	// only one with that likelihood.
+	best := func(keys []string, match func(string) int) (string, bool) {
+		var bestKeys []string
+		bestLikelihood := unlikely
+		for _, k := range keys {
+			switch l := match(k); {
+			case l > bestLikelihood:
+				bestKeys, bestLikelihood = []string{k}, l
+			case l == bestLikelihood && l != unlikely:
+				bestKeys = append(bestKeys, k)
+			}
+		}
+		if len(bestKeys) != 1 {
+			return "", false
+		}
+		return bestKeys[0], true
+	}
+
+	renamed := map[string]string{}
+	for _, oldKey := range removed {
+		newKey, ok := best(added, func(newKey string) int { return likelihood(oldKey, newKey) })
+		if !ok {
+			continue
+		}
+		if k, ok := best(removed, func(k string) int { return likelihood(k, newKey) }); ok && k == oldKey {
+			renamed[oldKey] = newKey
+		}
+	}
+	return renamed
+}
+
+// DiffValues returns the values added, removed, renamed and changed between
+// old and new, sorted by key. Renames are detected by renames. Keys matching
+// one of the ignore patterns are left out.
+func DiffValues(old, new map[string]any, ignore []string) []ValueChange {
+	var changes []ValueChange
+	var added, removed []string
+	for _, key := range slices.Sorted(maps.Keys(new)) {
+		if ignored(key, ignore) {
+			continue
+		}
+		oldValue, ok := old[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case !reflect.DeepEqual(oldValue, new[key]):
+			changes = append(changes, ValueChange{Key: key, Change: ValueChanged, OldValue: oldValue, NewValue: new[key]})
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(old)) {
+		if _, ok := new[key]; !ok && !ignored(key, ignore) {
+			removed = append(removed, key)
+		}
+	}
+
+	renamed := renames(old, new, removed, added)
+	renamedTo := map[string]bool{}
+	for _, oldKey := range removed {
+		newKey, ok := renamed[oldKey]
+		if !ok {
+			changes = append(changes, ValueChange{Key: oldKey, Change: ValueRemoved, OldValue: old[oldKey]})
+			continue
+		}
+		renamedTo[newKey] = true
+		changes = append(changes, ValueChange{Key: oldKey, NewKey: newKey, Change: ValueRenamed, OldValue: old[oldKey], NewValue: new[newKey]})
+	}
+	for _, key := range added {
+		if !renamedTo[key] {
+			changes = append(changes, ValueChange{Key: key, Change: ValueAdded, NewValue: new[key]})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b ValueChange) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return changes
+}
+
+// DiffValuesRefs returns the changes of the values file located at
+// valuesFile in the repository located in dir between base and head. A
+// values file missing from a ref has no values.
+func DiffValuesRefs(ctx context.Context, dir, valuesFile, base, head string, ignore []string) ([]ValueChange, error) {
+	load := func(ref string) (map[string]any, error) {
+		data, _, err := git.ShowFile(ctx, dir, ref, valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		values, err := ParseValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s at %s: %w", valuesFile, ref, err)
+		}
+		return values, nil
+	}
+	old, err := load(base)
+	if err != nil {
+		return nil, err
+	}
+	new, err := load(head)
+	if err != nil {
+		return nil, err
+	}
+	return DiffValues(old, new, ignore), nil
+}
+
+// valueChangeGroups lists the titles of the groups printed by
+// PrintValueChanges, in order. Changes that may break upgrades come first.
+var valueChangeGroups = []struct {
+	title  string
+	change string
+}{
+	{"Removed", ValueRemoved},
+	{"Renamed", ValueRenamed},
+	{"Default changed", ValueChanged},
+	{"Added", ValueAdded},
+}
+
+// PrintValueChanges writes the changes into w as a "Helm Changes" Markdown
+// section, grouped by kind of change.
+func PrintValueChanges(w io.Writer, changes []ValueChange) {
+	fmt.Fprintln(w, "**Helm Changes:**")
+	first := true
+	for _, group := range valueChangeGroups {
+		var bullets []string
+		for _, c := range changes {
+			if c.Change == group.change {
+				bullets = append(bullets, c.Markdown())
+			}
+		}
+		if len(bullets) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+		fmt.Fprintf(w, "*%s:*\n", group.title)
+		for _, bullet := range bullets {
+			fmt.Fprintln(w, bullet)
+		}
+	}
+}
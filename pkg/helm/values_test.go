@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cilium/release/pkg/git/gittest"
+)
+
+const oldValues = `
+debug:
+  enabled: false
+hubble:
+  relay:
+    enabled: false
+    image:
+      tag: v1.16.0
+      digest: sha256:aaaa
+      useDigest: true
+  tls:
+    auto:
+      method: helm
+kubeProxyReplacement: "false"
+bpf:
+  masquerade: ~
+  mapDynamicSizeRatio: 0.0025
+tolerations:
+- operator: Exists
+`
+
+const newValues = `
+debug:
+  enabled: false
+  verbose: ~
+hubble:
+  relay:
+    enabled: true
+    image:
+      tag: v1.16.1
+      digest: sha256:bbbb
+      useDigest: true
+  tls:
+    auto:
+      certManagerIssuerRef: {}
+      method: helm
+proxy:
+  kubeProxyReplacement: "false"
+bpf:
+  masquerade: ~
+tolerations:
+- operator: Exists
+- key: foo
+`
+
+func TestDiffValues(t *testing.T) {
+	old, err := ParseValues(oldValues)
+	assert.NoError(t, err)
+	new, err := ParseValues(newValues)
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:aaaa", old["hubble.relay.image.digest"])
+	assert.Equal(t, map[string]any{}, new["hubble.tls.auto.certManagerIssuerRef"])
+
+	changes := DiffValues(old, new, DefaultIgnoredValues)
+	assert.Equal(t, []ValueChange{
+		{Key: "bpf.mapDynamicSizeRatio", Change: ValueRemoved, OldValue: 0.0025},
+		{Key: "debug.verbose", Change: ValueAdded},
+		{Key: "hubble.relay.enabled", Change: ValueChanged, OldValue: false, NewValue: true},
+		{Key: "hubble.tls.auto.certManagerIssuerRef", Change: ValueAdded, NewValue: map[string]any{}},
+		{Key: "kubeProxyReplacement", NewKey: "proxy.kubeProxyReplacement", Change: ValueRenamed, OldValue: "false", NewValue: "false"},
+		{
+			Key:      "tolerations",
+			Change:   ValueChanged,
+			OldValue: []any{map[string]any{"operator": "Exists"}},
+			NewValue: []any{map[string]any{"operator": "Exists"}, map[string]any{"key": "foo"}},
+		},
+	}, changes)
+
+	var buf bytes.Buffer
+	PrintValueChanges(&buf, changes)
+	assert.Equal(t, "**Helm Changes:**\n"+
+		"*Removed:*\n"+
+		"* `bpf.mapDynamicSizeRatio` was removed, its default was `0.0025`\n"+
+		"\n"+
+		"*Renamed:*\n"+
+		"* `kubeProxyReplacement` was renamed to `proxy.kubeProxyReplacement`\n"+
+		"\n"+
+		"*Default changed:*\n"+
+		"* `hubble.relay.enabled` default changed from `false` to `true`\n"+
+		"* `tolerations` default changed from `[{\"operator\":\"Exists\"}]` to `[{\"operator\":\"Exists\"},{\"key\":\"foo\"}]`\n"+
+		"\n"+
+		"*Added:*\n"+
+		"* `debug.verbose` was added with default `null`\n"+
+		"* `hubble.tls.auto.certManagerIssuerRef` was added with default `{}`\n", buf.String())
+}
+
+func TestDiffValuesRenames(t *testing.T) {
+	old, err := ParseValues(`
+foo:
+  enabled: false
+  mode: native
+bar:
+  mode: native
+nodePort:
+  enabled: false
+  range: "30000,32767"
+hostPort:
+  enabled: false
+gatewayAPI:
+  enabled: false
+ingressController:
+  enabled: false
+`)
+	assert.NoError(t, err)
+	new, err := ParseValues(`
+foo:
+  mode: native
+bar:
+  enabled: false
+  mode: native
+kubeProxyReplacement:
+  nodePort:
+    enabled: false
+    range: "30000,32767"
+hostPort:
+  enabled: true
+gateway:
+  enabled: false
+ingress:
+  enabled: false
+`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []ValueChange{
+		// Both parents are still there and no sibling moved with it.
+		{Key: "bar.enabled", Change: ValueAdded, NewValue: false},
+		{Key: "foo.enabled", Change: ValueRemoved, OldValue: false},
+		// Both could be renamed into either new key.
+		{Key: "gateway.enabled", Change: ValueAdded, NewValue: false},
+		{Key: "gatewayAPI.enabled", Change: ValueRemoved, OldValue: false},
+		{Key: "hostPort.enabled", Change: ValueChanged, OldValue: false, NewValue: true},
+		{Key: "ingress.enabled", Change: ValueAdded, NewValue: false},
+		{Key: "ingressController.enabled", Change: ValueRemoved, OldValue: false},
+		// The whole section moved.
+		{Key: "nodePort.enabled", NewKey: "kubeProxyReplacement.nodePort.enabled", Change: ValueRenamed, OldValue: false, NewValue: false},
+		{Key: "nodePort.range", NewKey: "kubeProxyReplacement.nodePort.range", Change: ValueRenamed, OldValue: "30000,32767", NewValue: "30000,32767"},
+	}, DiffValues(old, new, nil))
+}
+
+func Test_ignored(t *testing.T) {
+	assert.True(t, ignored("image.tag", DefaultIgnoredValues))
+	assert.True(t, ignored("hubble.relay.image.digest", DefaultIgnoredValues))
+	assert.True(t, ignored("operator.image.awsDigest", DefaultIgnoredValues))
+	assert.False(t, ignored("operator.image.useDigest", DefaultIgnoredValues))
+	assert.False(t, ignored("tag", DefaultIgnoredValues))
+	assert.False(t, ignored("hubble.relay.image.tag", nil))
+}
+
+func TestDiffValuesRefs(t *testing.T) {
+	ctx := context.Background()
+	dir := gittest.NewRepo(t)
+	gittest.Commit(t, dir, "values", map[string]string{DefaultValuesFile: oldValues})
+	gittest.Run(t, dir, "tag", "v1.0.0")
+	gittest.Commit(t, dir, "values", map[string]string{DefaultValuesFile: newValues})
+
+	changes, err := DiffValuesRefs(ctx, dir, DefaultValuesFile, "v1.0.0", "main", DefaultIgnoredValues)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 6)
+
+	_, err = DiffValuesRefs(ctx, dir, DefaultValuesFile, "does-not-exist", "main", DefaultIgnoredValues)
+	assert.Error(t, err)
+}
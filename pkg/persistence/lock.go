@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked is returned by Lock if the state file is locked by another
+// process.
+var ErrLocked = errors.New("state file is in use by another process")
+
+// Lock takes an advisory lock on file, which is held until the returned
+// function is called. It fails with ErrLocked instead of waiting if another
+// process holds the lock. The lock is taken on a separate lock file since
+// file itself is replaced on every write.
+func Lock(file string) (func() error, error) {
+	lockFile := file + ".lock"
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0664)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file %s: %w", lockFile, err)
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to lock %s: %w", lockFile, err)
+	}
+	return func() error {
+		// The lock is released when the file is closed. The lock file isn't
+		// removed since another process may have opened it
+		// already and would then lock a file that no longer exists.
+		return f.Close()
+	}, nil
+}
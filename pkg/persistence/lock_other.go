@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package persistence
+
+import "os"
+
+// flock is a no-op on platforms without flock(2), where concurrent runs are
+// not detected.
+func flock(f *os.File) error {
+	return nil
+}
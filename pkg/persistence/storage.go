@@ -16,40 +16,235 @@ package persistence
 
 import (
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
 
 	"github.com/cilium/release/pkg/types"
 )
 
+// SchemaVersion is the version of the state file format written by Store.
+// State files written before the format was versioned are version 0.
+const SchemaVersion = 1
+
+// ErrMetadataMismatch is returned by Metadata.Validate if a state file was
+// created for a different repository or commit range.
+var ErrMetadataMismatch = errors.New("state file was created for a different range")
+
+// Metadata describes how a state file was created.
+type Metadata struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Base  string `json:"base"`
+	Head  string `json:"head"`
+	// LabelFilters is only informational: the filters are applied when the
+	// release notes are printed, so the stored PRs don't depend on them.
+	LabelFilters []string  `json:"labelFilters,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ToolVersion  string    `json:"toolVersion"`
+}
+
+// Validate returns an error wrapping ErrMetadataMismatch if m was not
+// created for the same repository and commit range as expected.
+func (m Metadata) Validate(expected Metadata) error {
+	return m.validate(expected, true)
+}
+
+// ValidateBase returns an error wrapping ErrMetadataMismatch if m was not
+// created for the same repository and base as expected, whatever its head.
+func (m Metadata) ValidateBase(expected Metadata) error {
+	return m.validate(expected, false)
+}
+
+func (m Metadata) validate(expected Metadata, checkHead bool) error {
+	var diffs []string
+	check := func(field, got, want string) {
+		if got != want {
+			diffs = append(diffs, fmt.Sprintf("%s is %q instead of %q", field, got, want))
+		}
+	}
+	check("owner", m.Owner, expected.Owner)
+	check("repo", m.Repo, expected.Repo)
+	check("base", m.Base, expected.Base)
+	if checkHead {
+		check("head", m.Head, expected.Head)
+	}
+	if len(diffs) != 0 {
+		return fmt.Errorf("%w: %v", ErrMetadataMismatch, diffs)
+	}
+	return nil
+}
+
 type State struct {
+	Version      int `json:"version"`
+	Metadata     Metadata
 	BackportPRs  types.BackportPRs
 	PullRequests types.PullRequests
 	NodeIDs      types.NodeIDs
 	SHAs         []string
+	// FirstPRs caches the number of the first merged PR of each author, or
+	// 0 if none was found.
+	FirstPRs map[string]int `json:",omitempty"`
+
+	// RestoredFrom is set by Load if the state was restored from a backup.
+	RestoredFrom string `json:"-"`
+	// Migrated is set by Load if the state was written by an older version
+	// of this tool.
+	Migrated bool `json:"-"`
 }
 
-func StoreState(file string, backportPRs types.BackportPRs, prs types.PullRequests, nodesIDs types.NodeIDs, shas []string) error {
-	s := State{
-		BackportPRs:  backportPRs,
-		PullRequests: prs,
-		NodeIDs:      nodesIDs,
-		SHAs:         shas,
+// migrations upgrade a state from the version used as key to the next one.
+// expected is the metadata of the state the caller of Load is looking for.
+var migrations = map[int]func(s *State, expected Metadata) error{
+	// Version 0 didn't record any metadata. Callers used to assume that the
+	// state file was created for the range they were looking for, so do the
+	// same instead of failing their validation.
+	0: func(s *State, expected Metadata) error {
+		s.Metadata = expected
+		s.Metadata.ToolVersion = "unknown"
+		return nil
+	},
+}
+
+func (s *State) migrate(expected Metadata) error {
+	if s.Version > SchemaVersion {
+		return fmt.Errorf("state file version %d is newer than the supported version %d, please update this tool", s.Version, SchemaVersion)
+	}
+	s.Migrated = s.Version < SchemaVersion
+	for s.Version < SchemaVersion {
+		migrate, ok := migrations[s.Version]
+		if !ok {
+			return fmt.Errorf("unable to migrate state file from version %d", s.Version)
+		}
+		if err := migrate(s, expected); err != nil {
+			return fmt.Errorf("unable to migrate state file from version %d: %w", s.Version, err)
+		}
+		s.Version++
+	}
+	return nil
+}
+
+// ToolVersion returns the version of this tool, as stored in its build info.
+func ToolVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, setting := range bi.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return bi.Main.Version
+}
+
+// Store atomically writes s into file with the current schema version. The
+// previous contents of file are kept in a backup file, see Load.
+func Store(file string, s *State) error {
+	s.Version = SchemaVersion
+	if s.Metadata.CreatedAt.IsZero() {
+		s.Metadata.CreatedAt = time.Now().UTC()
+	}
+	if s.Metadata.ToolVersion == "" {
+		s.Metadata.ToolVersion = ToolVersion()
 	}
 	data, err := json.MarshalIndent(s, "", " ")
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(file, data, 0664)
+	// Only rotate the previous state into the backup if it can be parsed,
+	// otherwise a corrupted state would replace the last good one.
+	if prev, err := os.ReadFile(file); err == nil {
+		if _, err := parse(prev); err == nil {
+			if err := writeFileAtomic(backupFile(file), prev, 0664); err != nil {
+				return fmt.Errorf("unable to back up state file: %w", err)
+			}
+		}
+	}
+	return writeFileAtomic(file, data, 0664)
 }
 
-func LoadState(file string) (types.BackportPRs, types.PullRequests, types.NodeIDs, []string, error) {
-	data, err := ioutil.ReadFile(file)
+// Load reads the state from file, migrating it to the current schema version
+// if it was written by an older version of this tool. The metadata that older
+// versions didn't record is taken from expected. If file can't be parsed, the
+// state is restored from the backup written by Store, and RestoredFrom is set
+// to the backup file.
+func Load(file string, expected Metadata) (*State, error) {
+	data, err := os.ReadFile(file)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, err
+	}
+	s, err := parse(data)
+	if err != nil {
+		bak, bakErr := os.ReadFile(backupFile(file))
+		if bakErr != nil {
+			return nil, err
+		}
+		s, bakErr = parse(bak)
+		if bakErr != nil {
+			return nil, err
+		}
+		s.RestoredFrom = backupFile(file)
+	}
+	if err := s.migrate(expected); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func parse(data []byte) (*State, error) {
+	s := &State{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func backupFile(file string) string {
+	return file + ".bak"
+}
+
+// writeFileAtomic writes data into a temporary file which, once synced to
+// disk, is renamed to file. Readers of file thus either see its previous or
+// its new contents, even if the process is interrupted.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
 	}
-	s := State{}
-	err = json.Unmarshal(data, &s)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func StoreState(file string, backportPRs types.BackportPRs, prs types.PullRequests, nodesIDs types.NodeIDs, shas []string) error {
+	return Store(file, &State{
+		BackportPRs:  backportPRs,
+		PullRequests: prs,
+		NodeIDs:      nodesIDs,
+		SHAs:         shas,
+	})
+}
+
+func LoadState(file string) (types.BackportPRs, types.PullRequests, types.NodeIDs, []string, error) {
+	s, err := Load(file, Metadata{})
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
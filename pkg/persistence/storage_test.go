@@ -15,8 +15,10 @@
 package persistence
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -100,3 +102,127 @@ func TestStoreState(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadMigratesUnversionedState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	data := `{"BackportPRs":{},"PullRequests":{"3":{"ReleaseNote":"FooBar"}},"NodeIDs":{"3":"abcdef"},"SHAs":["9ba79ef"]}`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Metadata{Owner: "cilium", Repo: "cilium", Base: "v1.0.0", Head: "v1.0.1"}
+	s, err := Load(file, expected)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Version != SchemaVersion || !s.Migrated {
+		t.Errorf("Load() version = %d, migrated = %v, want %d, true", s.Version, s.Migrated, SchemaVersion)
+	}
+	if err := s.Metadata.Validate(expected); err != nil {
+		t.Errorf("Load() metadata = %v, want %v: %v", s.Metadata, expected, err)
+	}
+	if s.PullRequests[3].ReleaseNote != "FooBar" || s.NodeIDs[3] != "abcdef" || !reflect.DeepEqual(s.SHAs, []string{"9ba79ef"}) {
+		t.Errorf("Load() state = %v", s)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"version":1000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file, expected); err == nil {
+		t.Error("Load() of a newer version should fail")
+	}
+}
+
+func TestStoreMetadata(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	md := Metadata{
+		Owner:        "cilium",
+		Repo:         "cilium",
+		Base:         "v1.16.0",
+		Head:         "v1.16.1",
+		LabelFilters: []string{"kind/bug"},
+	}
+	if err := Store(file, &State{Metadata: md}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	s, err := Load(file, Metadata{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Metadata.CreatedAt.IsZero() || s.Metadata.ToolVersion == "" {
+		t.Errorf("Store() didn't set the creation time and tool version: %v", s.Metadata)
+	}
+	if err := s.Metadata.Validate(md); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	md.Head = "v1.16.2"
+	if err := s.Metadata.Validate(md); !errors.Is(err, ErrMetadataMismatch) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrMetadataMismatch)
+	}
+	if err := s.Metadata.ValidateBase(md); err != nil {
+		t.Errorf("ValidateBase() error = %v", err)
+	}
+	md.Base = "v1.15.0"
+	if err := s.Metadata.ValidateBase(md); !errors.Is(err, ErrMetadataMismatch) {
+		t.Errorf("ValidateBase() error = %v, want %v", err, ErrMetadataMismatch)
+	}
+	md.Base = "v1.16.0"
+	md.Repo = "hubble"
+	if err := s.Metadata.ValidateBase(md); !errors.Is(err, ErrMetadataMismatch) {
+		t.Errorf("ValidateBase() error = %v, want %v", err, ErrMetadataMismatch)
+	}
+}
+
+func TestStoreKeepsBackup(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	if err := Store(file, &State{SHAs: []string{"first"}}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if err := Store(file, &State{SHAs: []string{"second"}}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	// A truncated state file is restored from the last good state.
+	if err := os.WriteFile(file, []byte(`{"version":1,"SHAs":["thi`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(file, Metadata{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.RestoredFrom != file+".bak" || !reflect.DeepEqual(s.SHAs, []string{"first"}) {
+		t.Errorf("Load() restored %v from %q", s.SHAs, s.RestoredFrom)
+	}
+
+	// The corrupted state doesn't replace the backup.
+	if err := Store(file, &State{SHAs: []string{"third"}}); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	s, err = Load(file+".bak", Metadata{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(s.SHAs, []string{"first"}) {
+		t.Errorf("Store() replaced the backup with %v", s.SHAs)
+	}
+}
+
+func TestLock(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	unlock, err := Lock(file)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := Lock(file); !errors.Is(err, ErrLocked) {
+		t.Errorf("Lock() error = %v, want %v", err, ErrLocked)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock() error = %v", err)
+	}
+	unlock, err = Lock(file)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	unlock()
+}
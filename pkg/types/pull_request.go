@@ -22,6 +22,14 @@ type PullRequest struct {
 	// PullRequest.
 	BackportBranches []string
 	Labels           []string
+	// Reverts contains the PRs reverted by the PullRequest.
+	Reverts []int `json:",omitempty"`
+	// UpgradeNotes contains the Markdown notes for users upgrading to a
+	// release that contains the PullRequest.
+	UpgradeNotes string `json:",omitempty"`
+	// Title is the title of the PullRequest. It is empty in state files
+	// created before it was recorded.
+	Title string `json:",omitempty"`
 }
 
 // NodeIDs maps a Pull Request number to its graphql node_id
@@ -73,6 +81,11 @@ func (in *PullRequest) DeepCopyInto(out *PullRequest) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Reverts != nil {
+		in, out := &in.Reverts, &out.Reverts
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 